@@ -14,7 +14,9 @@ func (authorService AuthorService) buildSuggestions(contentRef ContentRef) []sug
 	suggestions := []suggestion{}
 
 	for _, value := range authors {
-		suggestions = append(suggestions, buildSuggestion(value, authorURI, hasAuthor))
+		if suggestion, labelled := buildSuggestion(value, authorURI, hasAuthor); labelled {
+			suggestions = append(suggestions, suggestion)
+		}
 	}
 
 	return suggestions
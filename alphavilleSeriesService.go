@@ -14,7 +14,9 @@ func (alphavilleSeriesService AlphavilleSeriesService) buildSuggestions(contentR
 	suggestions := []suggestion{}
 
 	for _, value := range series {
-		suggestions = append(suggestions, buildSuggestion(value, alphavilleSeriesURI, classification))
+		if suggestion, labelled := buildSuggestion(value, alphavilleSeriesURI, classification); labelled {
+			suggestions = append(suggestions, suggestion)
+		}
 	}
 
 	return suggestions
@@ -1,17 +1,17 @@
 package main
 
 import (
-	"encoding/base64"
 	"encoding/json"
-	"encoding/xml"
+	"expvar"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"sync"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
-	"unicode/utf8"
 
 	"github.com/Financial-Times/message-queue-go-producer/producer"
 	"github.com/Financial-Times/message-queue-gonsumer/consumer"
@@ -23,7 +23,25 @@ import (
 )
 
 var messageProducer producer.MessageProducer
+var producerBreaker *CircuitBreaker
+var batchProducer *BatchProducer
+var rateLimitedProducer *RateLimitedProducer
 var taxonomyHandlers = make(map[string]TaxonomyService)
+var suggestionStore SuggestionStore
+var concordances *ConcordanceTable
+var messageFilter MessageFilter
+var mappingProfiles MappingProfiles
+var headerConfig HeaderConfig
+var workerPool *WorkerPool
+var primaryOutput = OutputVersion{Version: "1", Format: jsonFormat{}}
+var dualWriteOutput OutputVersion
+var dualWriteProducer producer.MessageProducer
+var deadLetterProducer producer.MessageProducer
+var dedupCache = NewDedupCache(0, 0)
+var staleEvents = &StaleEventGuard{policy: staleEventsProcess}
+var staleEventsProducer producer.MessageProducer
+var freshness = NewFreshnessTracker(10 * time.Minute)
+var errorRates = NewErrorRateTracker(ErrorRateConfig{Window: 5 * time.Minute})
 
 const messageTimestampDateFormat = "2006-01-02T15:04:05.000Z"
 
@@ -59,6 +77,66 @@ func main() {
 		Desc:   "Whether the consumer uses concurrent processing for the messages",
 		EnvVar: "SRC_CONCURRENT_PROCESSING",
 	})
+	workerPoolSize := app.Int(cli.IntOpt{
+		Name:   "worker-pool-size",
+		Value:  0,
+		Desc:   "Number of workers handling the messages in parallel, messages for the same content UUID are handled in order by the same worker. Messages are handled by the consumer when 0",
+		EnvVar: "WORKER_POOL_SIZE",
+	})
+	workerQueueSize := app.Int(cli.IntOpt{
+		Name:   "worker-queue-size",
+		Value:  100,
+		Desc:   "Number of messages of a batch waiting for each worker before dispatching the batch blocks",
+		EnvVar: "WORKER_QUEUE_SIZE",
+	})
+	sourceAllowedMessageTypes := app.Strings(cli.StringsOpt{
+		Name:   "source-allowed-message-types",
+		Value:  []string{},
+		Desc:   "Message-Type header values which are processed. All message types are processed when empty",
+		EnvVar: "SRC_ALLOWED_MESSAGE_TYPES",
+	})
+	sourceDeniedMessageTypes := app.Strings(cli.StringsOpt{
+		Name:   "source-denied-message-types",
+		Value:  []string{},
+		Desc:   "Message-Type header values which are skipped",
+		EnvVar: "SRC_DENIED_MESSAGE_TYPES",
+	})
+	sourceAllowedOriginSystems := app.Strings(cli.StringsOpt{
+		Name:   "source-allowed-origin-systems",
+		Value:  []string{},
+		Desc:   "Origin-System-Id header values which are processed. All origin systems are processed when empty",
+		EnvVar: "SRC_ALLOWED_ORIGIN_SYSTEMS",
+	})
+	sourceDeniedOriginSystems := app.Strings(cli.StringsOpt{
+		Name:   "source-denied-origin-systems",
+		Value:  []string{},
+		Desc:   "Origin-System-Id header values which are skipped",
+		EnvVar: "SRC_DENIED_ORIGIN_SYSTEMS",
+	})
+	sourceUTF8Repair := app.String(cli.StringOpt{
+		Name:   "source-utf8-repair",
+		Value:  "",
+		Desc:   "How metadata with invalid UTF-8 is repaired before being parsed again: replace the invalid bytes with U+FFFD, or reinterpret them as windows1252. Such metadata is dropped when empty",
+		EnvVar: "SRC_UTF8_REPAIR",
+	})
+	sourceMaxMetadataBytes := app.Int(cli.IntOpt{
+		Name:   "source-max-metadata-bytes",
+		Value:  xmlLimits.MaxBytes,
+		Desc:   "Maximum size in bytes of the metadata XML, larger metadata fails. Unlimited when 0",
+		EnvVar: "SRC_MAX_METADATA_BYTES",
+	})
+	sourceMaxMetadataElements := app.Int(cli.IntOpt{
+		Name:   "source-max-metadata-elements",
+		Value:  xmlLimits.MaxElements,
+		Desc:   "Maximum number of elements in the metadata XML, decoding stops beyond. Unlimited when 0",
+		EnvVar: "SRC_MAX_METADATA_ELEMENTS",
+	})
+	sourceMaxMetadataDepth := app.Int(cli.IntOpt{
+		Name:   "source-max-metadata-depth",
+		Value:  xmlLimits.MaxDepth,
+		Desc:   "Maximum nesting depth of the elements in the metadata XML, decoding stops beyond. Unlimited when 0",
+		EnvVar: "SRC_MAX_METADATA_DEPTH",
+	})
 	destinationAddress := app.String(cli.StringOpt{
 		Name:   "destination-address",
 		Value:  "",
@@ -77,6 +155,216 @@ func main() {
 		Desc:   "The queue used by the producer",
 		EnvVar: "DEST_QUEUE",
 	})
+	dedupWindow := app.Int(cli.IntOpt{
+		Name:   "dedup-window-seconds",
+		Value:  600,
+		Desc:   "Messages with a Message-Id seen within this many seconds are skipped as duplicates. Disabled when 0",
+		EnvVar: "DEDUP_WINDOW_SECONDS",
+	})
+	dedupMaxMessages := app.Int(cli.IntOpt{
+		Name:   "dedup-max-messages",
+		Value:  100000,
+		Desc:   "Number of Message-Ids remembered to skip duplicates, the oldest are forgotten first",
+		EnvVar: "DEDUP_MAX_MESSAGES",
+	})
+	staleEventPolicy := app.String(cli.StringOpt{
+		Name:   "stale-event-policy",
+		Value:  staleEventsProcess,
+		Desc:   "What to do with events created before the last processed event for the same content: process, skip or route to the stale-events-topic. Use process for replays",
+		EnvVar: "STALE_EVENT_POLICY",
+	})
+	staleEventsTopic := app.String(cli.StringOpt{
+		Name:   "stale-events-topic",
+		Value:  "",
+		Desc:   "The topic stale events are routed to, with the route stale event policy",
+		EnvVar: "STALE_EVENTS_TOPIC",
+	})
+	staleEventsMaxContents := app.Int(cli.IntOpt{
+		Name:   "stale-events-max-contents",
+		Value:  100000,
+		Desc:   "Number of content UUIDs for which the last processed event is remembered",
+		EnvVar: "STALE_EVENTS_MAX_CONTENTS",
+	})
+	destinationBatchSize := app.Int(cli.IntOpt{
+		Name:   "destination-batch-size",
+		Value:  0,
+		Desc:   "Maximum number of messages published in one request to the proxy. Messages are published one by one when 0 or 1. Batches need a worker-pool-size above 1 to fill up",
+		EnvVar: "DEST_BATCH_SIZE",
+	})
+	destinationBatchLinger := app.Int(cli.IntOpt{
+		Name:   "destination-batch-linger-millis",
+		Value:  50,
+		Desc:   "How long in milliseconds a batch waits for more messages before being published",
+		EnvVar: "DEST_BATCH_LINGER_MILLIS",
+	})
+	destinationOutputFormat := app.String(cli.StringOpt{
+		Name:   "destination-output-format",
+		Value:  "json",
+		Desc:   "The format of the concept suggestions sent: json, jsonld, ntriples or turtle",
+		EnvVar: "DEST_OUTPUT_FORMAT",
+	})
+	destinationSchemaVersion := app.String(cli.StringOpt{
+		Name:   "destination-schema-version",
+		Value:  "1",
+		Desc:   "The schema version of the concept suggestions sent: 1, or 2 in JSON only",
+		EnvVar: "DEST_SCHEMA_VERSION",
+	})
+	destinationDualWriteTopic := app.String(cli.StringOpt{
+		Name:   "destination-dual-write-topic",
+		Value:  "",
+		Desc:   "The topic the concept suggestions are also sent to in destination-dual-write-schema-version, during a schema migration. Disabled when empty",
+		EnvVar: "DEST_DUAL_WRITE_TOPIC",
+	})
+	destinationDualWriteSchemaVersion := app.String(cli.StringOpt{
+		Name:   "destination-dual-write-schema-version",
+		Value:  "2",
+		Desc:   "The schema version of the concept suggestions sent to destination-dual-write-topic: 1, or 2 in JSON only",
+		EnvVar: "DEST_DUAL_WRITE_SCHEMA_VERSION",
+	})
+	destinationDualWriteOutputFormat := app.String(cli.StringOpt{
+		Name:   "destination-dual-write-output-format",
+		Value:  "json",
+		Desc:   "The format of the concept suggestions sent to destination-dual-write-topic: json, jsonld, ntriples or turtle",
+		EnvVar: "DEST_DUAL_WRITE_OUTPUT_FORMAT",
+	})
+	destinationValidateSchema := app.Bool(cli.BoolOpt{
+		Name:   "destination-validate-schema",
+		Value:  false,
+		Desc:   "Whether the concept suggestions are validated against their JSON schema before being sent. Invalid ones are sent to the dead-letter topic",
+		EnvVar: "DEST_VALIDATE_SCHEMA",
+	})
+	destinationDeadLetterTopic := app.String(cli.StringOpt{
+		Name:   "destination-dead-letter-topic",
+		Value:  "",
+		Desc:   "The topic invalid concept suggestions are sent to. They are only logged when empty",
+		EnvVar: "DEST_DEAD_LETTER_TOPIC",
+	})
+	destinationRateLimit := app.String(cli.StringOpt{
+		Name:   "destination-rate-limit",
+		Value:  "0",
+		Desc:   "Maximum number of messages sent per second, consumption slows down to match. Unlimited when 0. Can be changed through the admin endpoint",
+		EnvVar: "DEST_RATE_LIMIT",
+	})
+	destinationCompression := app.String(cli.StringOpt{
+		Name:   "destination-compression",
+		Value:  "",
+		Desc:   "The compression of the concept suggestions sent, gzip or zstd, in their Content-Encoding header. Uncompressed when empty",
+		EnvVar: "DEST_COMPRESSION",
+	})
+	destinationRateLimitBurst := app.Int(cli.IntOpt{
+		Name:   "destination-rate-limit-burst",
+		Value:  10,
+		Desc:   "Number of messages which can be sent at once over the rate limit",
+		EnvVar: "DEST_RATE_LIMIT_BURST",
+	})
+	destinationOriginSystemID := app.String(cli.StringOpt{
+		Name:   "destination-origin-system-id",
+		Value:  "",
+		Desc:   "Origin-System-Id header of the concept suggestions, e.g. the system code of the suggestor. The source Origin-System-Id is kept when empty",
+		EnvVar: "DEST_ORIGIN_SYSTEM_ID",
+	})
+	destinationPassThroughHeaders := app.Strings(cli.StringsOpt{
+		Name:   "destination-pass-through-headers",
+		Value:  []string{},
+		Desc:   "Source message headers copied onto the concept suggestions, unless set by the suggestor",
+		EnvVar: "DEST_PASS_THROUGH_HEADERS",
+	})
+	storePath := app.String(cli.StringOpt{
+		Name:   "store-path",
+		Value:  "",
+		Desc:   "Directory where the last emitted suggestions are persisted per content. Leave empty to disable the store",
+		EnvVar: "STORE_PATH",
+	})
+	storeHistorySize := app.Int(cli.IntOpt{
+		Name:   "store-history-size",
+		Value:  10,
+		Desc:   "How many suggestion records are kept per content in the store",
+		EnvVar: "STORE_HISTORY_SIZE",
+	})
+	concordanceFile := app.String(cli.StringOpt{
+		Name:   "concordance-file",
+		Value:  "",
+		Desc:   "JSON file mapping V1 terms to existing thing URIs, consulted before generating thing IDs",
+		EnvVar: "CONCORDANCE_FILE",
+	})
+	mappingProfilesFile := app.String(cli.StringOpt{
+		Name:   "mapping-profiles-file",
+		Value:  "",
+		Desc:   "JSON file with the taxonomy mapping profiles per origin system or external source. All taxonomies are mapped for all content when empty",
+		EnvVar: "MAPPING_PROFILES_FILE",
+	})
+	freshnessWindow := app.Int(cli.IntOpt{
+		Name:   "freshness-window-seconds",
+		Value:  600,
+		Desc:   "The freshness health check fails when a message was received more than this many seconds ago and none was processed since",
+		EnvVar: "FRESHNESS_WINDOW_SECONDS",
+	})
+	errorRateWindow := app.Int(cli.IntOpt{
+		Name:   "error-rate-window-seconds",
+		Value:  300,
+		Desc:   "The window in seconds over which the message processing error rate is computed, at least 60",
+		EnvVar: "ERROR_RATE_WINDOW_SECONDS",
+	})
+	errorRateMinMessages := app.Int(cli.IntOpt{
+		Name:   "error-rate-min-messages",
+		Value:  10,
+		Desc:   "The least number of messages in the window before the error rate can fail the checks",
+		EnvVar: "ERROR_RATE_MIN_MESSAGES",
+	})
+	errorRateThreshold := app.String(cli.StringOpt{
+		Name:   "error-rate-threshold",
+		Value:  "0.1",
+		Desc:   "The error rate, between 0 and 1, above which the error rate health check fails",
+		EnvVar: "ERROR_RATE_THRESHOLD",
+	})
+	errorRateCriticalThreshold := app.String(cli.StringOpt{
+		Name:   "error-rate-critical-threshold",
+		Value:  "0.5",
+		Desc:   "The error rate, between 0 and 1, above which the service is not good to go when error-rate-fail-gtg is set",
+		EnvVar: "ERROR_RATE_CRITICAL_THRESHOLD",
+	})
+	errorRateSeverity := app.Int(cli.IntOpt{
+		Name:   "error-rate-severity",
+		Value:  2,
+		Desc:   "The severity of the error rate health check, 1, 2 or 3",
+		EnvVar: "ERROR_RATE_SEVERITY",
+	})
+	errorRateFailGTG := app.Bool(cli.BoolOpt{
+		Name:   "error-rate-fail-gtg",
+		Value:  false,
+		Desc:   "Whether the service is not good to go when the error rate is over the critical threshold",
+		EnvVar: "ERROR_RATE_FAIL_GTG",
+	})
+	breakerFailures := app.Int(cli.IntOpt{
+		Name:   "producer-breaker-failures",
+		Value:  5,
+		Desc:   "Consecutive failures to send messages after which consumption is paused until the write queue is reachable. Disabled when 0",
+		EnvVar: "PRODUCER_BREAKER_FAILURES",
+	})
+	breakerProbeInterval := app.Int(cli.IntOpt{
+		Name:   "producer-breaker-probe-seconds",
+		Value:  10,
+		Desc:   "How often in seconds the write queue connectivity is checked while the circuit breaker is open",
+		EnvVar: "PRODUCER_BREAKER_PROBE_SECONDS",
+	})
+	shutdownTimeout := app.Int(cli.IntOpt{
+		Name:   "shutdown-timeout-seconds",
+		Value:  30,
+		Desc:   "How long in seconds to wait for the in-flight messages, and then for the HTTP server, when shutting down",
+		EnvVar: "SHUTDOWN_TIMEOUT_SECONDS",
+	})
+	pauseStateFile := app.String(cli.StringOpt{
+		Name:   "pause-state-file",
+		Value:  "",
+		Desc:   "File keeping whether message consumption is paused across restarts. The paused state is not persisted when empty",
+		EnvVar: "PAUSE_STATE_FILE",
+	})
+	logLevel := app.String(cli.StringOpt{
+		Name:   "log-level",
+		Value:  "info",
+		Desc:   "Logging level, set to debug to log the skipped messages",
+		EnvVar: "LOG_LEVEL",
+	})
 
 	app.Action = func() {
 		httpClient := &http.Client{
@@ -105,25 +393,104 @@ func main() {
 			Queue: *destinationQueue,
 		}
 
-		initLogs(os.Stdout, os.Stdout, os.Stderr)
+		messageFilter = MessageFilter{
+			AllowedMessageTypes:  *sourceAllowedMessageTypes,
+			DeniedMessageTypes:   *sourceDeniedMessageTypes,
+			AllowedOriginSystems: *sourceAllowedOriginSystems,
+			DeniedOriginSystems:  *sourceDeniedOriginSystems,
+		}
+
+		debugHandle := ioutil.Discard
+		if strings.EqualFold(*logLevel, "debug") {
+			debugHandle = os.Stdout
+		}
+		headerConfig = HeaderConfig{
+			OriginSystemID: *destinationOriginSystemID,
+			PassThrough:    *destinationPassThroughHeaders,
+		}
+
+		initLogs(debugHandle, os.Stdout, os.Stdout, os.Stderr)
 		infoLogger.Printf("[Startup] Using source configuration: %# v", pretty.Formatter(srcConf))
+		infoLogger.Printf("[Startup] Using message filter: %# v", pretty.Formatter(messageFilter))
 		infoLogger.Printf("[Startup] Using dest configuration: %# v", pretty.Formatter(destConf))
+		infoLogger.Printf("[Startup] Using dest headers: %# v", pretty.Formatter(headerConfig))
 
 		setupTaxonomyHandlers()
+		initializeConcordances(*concordanceFile)
 
 		infoLogger.Printf("[Startup] Handling taxonomies:")
 		for key := range taxonomyHandlers {
 			infoLogger.Printf("\t %v", key)
 		}
+		initializeMappingProfiles(*mappingProfilesFile)
+
+		initializeStore(*storePath, *storeHistorySize)
+		initializeFreshness(time.Duration(*freshnessWindow) * time.Second)
+		initializeErrorRates(ErrorRateConfig{
+			Window:            time.Duration(*errorRateWindow) * time.Second,
+			MinMessages:       *errorRateMinMessages,
+			Threshold:         parseRate("error-rate-threshold", *errorRateThreshold),
+			CriticalThreshold: parseRate("error-rate-critical-threshold", *errorRateCriticalThreshold),
+			Severity:          parseSeverity("error-rate-severity", *errorRateSeverity),
+			FailGTG:           *errorRateFailGTG,
+		})
+		initializeUTF8Repair(*sourceUTF8Repair)
+		xmlLimits = XMLLimits{MaxBytes: *sourceMaxMetadataBytes, MaxElements: *sourceMaxMetadataElements, MaxDepth: *sourceMaxMetadataDepth}
+		infoLogger.Printf("[Startup] Metadata XML limits: %# v", pretty.Formatter(xmlLimits))
+		initializeDedupCache(time.Duration(*dedupWindow)*time.Second, *dedupMaxMessages)
+		initializeStaleEvents(*staleEventPolicy, *staleEventsMaxContents, destConf, *staleEventsTopic, httpClient)
+		initializeOutputs(*destinationOutputFormat, *destinationSchemaVersion, *destinationDualWriteOutputFormat, *destinationDualWriteSchemaVersion, *destinationValidateSchema)
+		initializeOutputProducers(destConf, *destinationDualWriteTopic, *destinationDeadLetterTopic, httpClient)
+		initializeWorkerPool(*workerPoolSize, *workerQueueSize, *sourceConcurrentProcessing)
+		initializeProducer(destConf, httpClient, *destinationBatchSize, time.Duration(*destinationBatchLinger)*time.Millisecond,
+			*workerPoolSize, *breakerFailures, time.Duration(*breakerProbeInterval)*time.Second)
+		initializeRateLimit(*destinationRateLimit, *destinationRateLimitBurst)
+		initializeCompression(*destinationCompression)
+		messageConsumer := NewPausableConsumer(func() consumer.MessageConsumer {
+			return initializeConsumer(srcConf, httpClient)
+		}, *pauseStateFile)
+		if messageConsumer.Paused() {
+			warnLogger.Printf("[Startup] Message consumption is paused")
+		}
+		producerBreaker.OnOpen = messageConsumer.Suspend
+		producerBreaker.OnClose = messageConsumer.Unsuspend
 
-		initializeProducer(destConf, httpClient)
-		messageConsumer := initializeConsumer(srcConf, httpClient)
-
-		go enableHealthChecks(messageConsumer)
+		shutdown := NewShutdown(messageConsumer, time.Duration(*shutdownTimeout)*time.Second)
+		if workerPool != nil {
+			shutdown.addFlusher(workerPool.Close)
+		}
+		if batchProducer != nil {
+			shutdown.addFlusher(batchProducer.Close)
+		}
+		server := enableHealthChecks(messageConsumer, shutdown)
 
-		readMessages(messageConsumer)
+		readMessages(messageConsumer, shutdown, server)
 	}
 
+	app.Command("transform", "Write the concept suggestions for the metadata read from the standard input", func(cmd *cli.Cmd) {
+		cmd.Spec = "[--content-type] [--output-format] UUID"
+		contentUUID := cmd.StringArg("UUID", "", "The UUID of the content the metadata belongs to")
+		contentType := cmd.StringOpt("content-type", "", "The media type of the metadata, recognised from the metadata when empty")
+		outputFormat := cmd.StringOpt("output-format", "json", "The format of the concept suggestions written: json, jsonld, ntriples or turtle")
+
+		cmd.Action = func() {
+			initLogs(ioutil.Discard, ioutil.Discard, os.Stderr, os.Stderr)
+			format, err := selectOutputFormat(*outputFormat)
+			if err != nil {
+				errorLogger.Printf("Invalid output-format: %v", err)
+				cli.Exit(1)
+			}
+			setupTaxonomyHandlers()
+			initializeConcordances(*concordanceFile)
+			initializeMappingProfiles(*mappingProfilesFile)
+			xmlLimits = XMLLimits{MaxBytes: *sourceMaxMetadataBytes, MaxElements: *sourceMaxMetadataElements, MaxDepth: *sourceMaxMetadataDepth}
+			if err := runTransform(os.Stdin, os.Stdout, *contentUUID, *contentType, format); err != nil {
+				errorLogger.Printf("Error transforming the metadata for UUID [%s]: %v", *contentUUID, err)
+				cli.Exit(1)
+			}
+		}
+	})
+
 	app.Run(os.Args)
 }
 
@@ -141,8 +508,17 @@ func setupTaxonomyHandlers() {
 	taxonomyHandlers["brands"] = BrandService{HandledTaxonomy: "Brands"}
 }
 
-func enableHealthChecks(messageConsumer consumer.MessageConsumer) {
+func enableHealthChecks(messageConsumer *PausableConsumer, shutdown *Shutdown) *http.Server {
 	hc := NewHealthCheck(messageProducer, messageConsumer)
+	hc.addGTGCheck(shutdown.Check)
+	hc.addCheck(pausedCheck(messageConsumer))
+	hc.addGTGCheck(messageConsumer.PausedCheck)
+	hc.addCheck(circuitBreakerCheck(producerBreaker))
+	hc.addCheck(freshnessCheck(freshness))
+	hc.addCheck(errorRateCheck(errorRates))
+	if errorRates.config.FailGTG {
+		hc.addGTGCheck(errorRates.CriticalCheck)
+	}
 	router := mux.NewRouter()
 	router.HandleFunc("/__health", hc.Health())
 	router.HandleFunc("/__gtg", status.NewGoodToGoHandler(hc.GTG))
@@ -150,108 +526,464 @@ func enableHealthChecks(messageConsumer consumer.MessageConsumer) {
 	router.HandleFunc(status.PingPathDW, status.PingHandler)
 	router.HandleFunc(status.BuildInfoPath, status.BuildInfoHandler)
 	router.HandleFunc(status.BuildInfoPathDW, status.BuildInfoHandler)
+	ch := NewConcordanceHandler(concordances)
+	router.HandleFunc("/__admin/concordances", ch.Lookup).Methods("GET")
+	router.HandleFunc("/__admin/concordances/reload", ch.Reload).Methods("POST")
+	ph := NewPauseHandler(messageConsumer)
+	router.HandleFunc("/schemas/concept-suggestion/{version}.json", GetSchema).Methods("GET")
+	router.HandleFunc("/transform/{uuid}", Transform).Methods("POST")
+	router.HandleFunc("/__admin/pause", ph.Pause).Methods("POST")
+	router.HandleFunc("/__admin/resume", ph.Resume).Methods("POST")
+	rh := NewRateLimitHandler(rateLimitedProducer)
+	router.HandleFunc("/__admin/rate-limit", rh.GetLimit).Methods("GET")
+	router.HandleFunc("/__admin/rate-limit", rh.SetLimit).Methods("PUT")
+	if suggestionStore != nil {
+		sh := NewSuggestionsHandler(suggestionStore)
+		router.HandleFunc("/content/{uuid}/suggestions", sh.GetSuggestions).Methods("GET")
+		router.HandleFunc("/content/{uuid}/history", sh.GetHistory).Methods("GET")
+	}
 	http.Handle("/", router)
-	err := http.ListenAndServe(":8080", nil)
+	server := &http.Server{Addr: ":8080"}
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			errorLogger.Panicf("Couldn't set up HTTP listener: %v\n", err)
+		}
+	}()
+	return server
+}
+
+func initializeFreshness(window time.Duration) {
+	freshness = NewFreshnessTracker(window)
+	expvar.Publish("lastProcessedTimestamp", expvar.Func(func() interface{} {
+		return formatTimestamp(freshness.LastProcessed())
+	}))
+}
+
+func initializeErrorRates(config ErrorRateConfig) {
+	errorRates = NewErrorRateTracker(config)
+	infoLogger.Printf("[Startup] Using error rate configuration: %# v", pretty.Formatter(errorRates.config))
+}
+
+func parseRate(name string, value string) float64 {
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 || rate > 1 {
+		errorLogger.Panicf("Invalid %s [%s], expected a number between 0 and 1\n", name, value)
+	}
+	return rate
+}
+
+func parseSeverity(name string, value int) uint8 {
+	if err := validateSeverity(value); err != nil {
+		errorLogger.Panicf("Invalid %s: %v", name, err)
+	}
+	return uint8(value)
+}
+
+func initializeMappingProfiles(file string) {
+	profiles, err := newMappingProfiles(file, taxonomyHandlers)
+	if err != nil {
+		errorLogger.Panicf("Couldn't load mapping profiles from [%s]: %v\n", file, err)
+	}
+	mappingProfiles = profiles
+	for _, profile := range append([]MappingProfile{mappingProfiles.Default}, mappingProfiles.Profiles...) {
+		infoLogger.Printf("[Startup] Mapping profile [%s] for origin systems %v and external sources %v maps taxonomies %v",
+			profile.Name, profile.OriginSystems, profile.ExternalSources, profile.taxonomies())
+	}
+}
+
+func initializeConcordances(file string) {
+	table, err := NewConcordanceTable(file)
+	if err != nil {
+		errorLogger.Panicf("Couldn't load concordances from [%s]: %v\n", file, err)
+	}
+	concordances = table
+	infoLogger.Printf("[Startup] Loaded [%d] concordances", concordances.Size())
+}
+
+func initializeStore(path string, historySize int) {
+	if path == "" {
+		infoLogger.Printf("[Startup] Suggestion store is disabled")
+		return
+	}
+	store, err := NewFileSuggestionStore(path, historySize)
+	if err != nil {
+		errorLogger.Panicf("Couldn't set up suggestion store at [%s]: %v\n", path, err)
+	}
+	suggestionStore = store
+	infoLogger.Printf("[Startup] Suggestion store: %# v", pretty.Formatter(suggestionStore))
+}
+
+func initializeProducer(config producer.MessageProducerConfig, client *http.Client, batchSize int, batchLinger time.Duration,
+	workerPoolSize int, breakerFailures int, breakerProbeInterval time.Duration) {
+	if err := validateBatchSize(batchSize, workerPoolSize); err != nil {
+		errorLogger.Panicf("Invalid destination-batch-size: %v", err)
+	}
+	p := producer.NewMessageProducerWithHTTPClient(config, client)
+	infoLogger.Printf("[Startup] Producer: %# v", pretty.Formatter(p))
+	if batchSize > 1 {
+		batchProducer = NewBatchProducer(config, client, p, batchSize, batchLinger)
+		infoLogger.Printf("[Startup] Publishing batches of up to [%d] messages, waiting up to %v", batchSize, batchLinger)
+		p = batchProducer
+	}
+	producerBreaker = NewCircuitBreaker(p, breakerFailures, breakerProbeInterval)
+	messageProducer = producerBreaker
+}
+
+func initializeUTF8Repair(strategy string) {
+	if err := validateUTF8Repair(strategy); err != nil {
+		errorLogger.Panicf("Invalid source-utf8-repair: %v", err)
+	}
+	utf8Repair = strategy
+	if strategy != "" {
+		infoLogger.Printf("[Startup] Repairing metadata with invalid UTF-8 with [%s]", strategy)
+	}
+}
+
+func initializeDedupCache(window time.Duration, maxMessages int) {
+	dedupCache = NewDedupCache(window, maxMessages)
+	expvar.Publish("dedupCacheSize", expvar.Func(func() interface{} {
+		return dedupCache.Size()
+	}))
+}
+
+func initializeStaleEvents(policy string, maxContents int, config producer.MessageProducerConfig, topic string, client *http.Client) {
+	guard, err := NewStaleEventGuard(policy, maxContents)
+	if err != nil {
+		errorLogger.Panicf("Invalid stale-event-policy or stale-events-max-contents: %v", err)
+	}
+	staleEvents = guard
+	if policy != staleEventsRoute {
+		return
+	}
+	if topic == "" {
+		errorLogger.Panicf("The stale-events-topic is required with the %s stale event policy", staleEventsRoute)
+	}
+	config.Topic = topic
+	staleEventsProducer = producer.NewMessageProducerWithHTTPClient(config, client)
+	infoLogger.Printf("[Startup] Stale events producer: %# v", pretty.Formatter(staleEventsProducer))
+}
+
+func initializeOutputs(formatName string, version string, dualWriteFormatName string, dualWriteVersion string, validate bool) {
+	format, err := selectOutputFormat(formatName)
+	if err != nil {
+		errorLogger.Panicf("Invalid destination-output-format: %v", err)
+	}
+	primaryOutput, err = NewOutputVersion(version, format, validate)
+	if err != nil {
+		errorLogger.Panicf("Invalid destination-schema-version: %v", err)
+	}
+	dualWriteFormat, err := selectOutputFormat(dualWriteFormatName)
+	if err != nil {
+		errorLogger.Panicf("Invalid destination-dual-write-output-format: %v", err)
+	}
+	dualWriteOutput, err = NewOutputVersion(dualWriteVersion, dualWriteFormat, validate)
+	if err != nil {
+		errorLogger.Panicf("Invalid destination-dual-write-schema-version: %v", err)
+	}
+	infoLogger.Printf("[Startup] Sending concept suggestions in schema version [%s] as [%s]", version, format.ContentType())
+}
+
+func initializeOutputProducers(config producer.MessageProducerConfig, dualWriteTopic string, deadLetterTopic string, client *http.Client) {
+	if dualWriteTopic != "" {
+		dualWriteConfig := config
+		dualWriteConfig.Topic = dualWriteTopic
+		dualWriteProducer = producer.NewMessageProducerWithHTTPClient(dualWriteConfig, client)
+		infoLogger.Printf("[Startup] Also sending concept suggestions in schema version [%s] as [%s] with producer: %# v", dualWriteOutput.Version, dualWriteOutput.Format.ContentType(), pretty.Formatter(dualWriteProducer))
+	}
+	if deadLetterTopic != "" {
+		deadLetterConfig := config
+		deadLetterConfig.Topic = deadLetterTopic
+		deadLetterProducer = producer.NewMessageProducerWithHTTPClient(deadLetterConfig, client)
+		infoLogger.Printf("[Startup] Dead-letter producer: %# v", pretty.Formatter(deadLetterProducer))
+	}
+}
+
+func initializeWorkerPool(size int, queueSize int, concurrentProcessing bool) {
+	if size <= 0 {
+		return
+	}
+	if concurrentProcessing {
+		warnLogger.Printf("[Startup] Source concurrent processing is ignored, the batches of messages consumed are handled by the worker pool")
+	}
+	workerPool = NewWorkerPool(size, queueSize, handleEvent)
+	expvar.Publish("workerQueueDepth", expvar.Func(func() interface{} {
+		return workerPool.QueueDepths()
+	}))
+	infoLogger.Printf("[Startup] Using a pool of [%d] workers with queues of [%d] messages", size, queueSize)
+}
+
+func initializeRateLimit(messagesPerSecond string, burst int) {
+	rate, err := strconv.ParseFloat(messagesPerSecond, 64)
+	if err != nil {
+		errorLogger.Panicf("Invalid destination-rate-limit [%s]: %v", messagesPerSecond, err)
+	}
+	rateLimitedProducer, err = NewRateLimitedProducer(messageProducer, RateLimit{MessagesPerSecond: rate, Burst: burst})
 	if err != nil {
-		errorLogger.Panicf("Couldn't set up HTTP listener: %v\n", err)
+		errorLogger.Panicf("Invalid destination-rate-limit: %v", err)
 	}
+	messageProducer = rateLimitedProducer
+	expvar.Publish("rateLimit", expvar.Func(func() interface{} {
+		return rateLimitedProducer.Limit()
+	}))
+	infoLogger.Printf("[Startup] Using rate limit: %# v", pretty.Formatter(rateLimitedProducer.Limit()))
 }
 
-func initializeProducer(config producer.MessageProducerConfig, client *http.Client) {
-	messageProducer = producer.NewMessageProducerWithHTTPClient(config, client)
-	infoLogger.Printf("[Startup] Producer: %# v", pretty.Formatter(messageProducer))
+func initializeCompression(encoding string) {
+	compressingProducer, err := NewCompressingProducer(messageProducer, encoding)
+	if err != nil {
+		errorLogger.Panicf("Invalid destination-compression: %v", err)
+	}
+	messageProducer = compressingProducer
+	if dualWriteProducer != nil {
+		dualWriteProducer, _ = NewCompressingProducer(dualWriteProducer, encoding)
+	}
+	if encoding != "" {
+		infoLogger.Printf("[Startup] Compressing the concept suggestions sent with [%s]", encoding)
+	}
 }
 
+// initializeConsumer returns a consumer handling the messages one by one, or by batch on the worker pool when enabled
 func initializeConsumer(config consumer.QueueConfig, client *http.Client) consumer.MessageConsumer {
-	messageConsumer := consumer.NewConsumer(config, handleMessage, client)
+	var messageConsumer consumer.MessageConsumer
+	if workerPool != nil {
+		messageConsumer = consumer.NewBatchedConsumer(config, consumeBatch, client)
+	} else {
+		messageConsumer = consumer.NewConsumer(config, consumeMessage, client)
+	}
 	infoLogger.Printf("[Startup] Consumer: %# v", pretty.Formatter(messageConsumer))
 	return messageConsumer
 }
 
-func readMessages(messageConsumer consumer.MessageConsumer) {
-	var consumerWaitGroup sync.WaitGroup
-	consumerWaitGroup.Add(1)
+func readMessages(messageConsumer consumer.MessageConsumer, shutdown *Shutdown, server *http.Server) {
+	go messageConsumer.Start()
 
-	go func() {
-		messageConsumer.Start()
-		consumerWaitGroup.Done()
-	}()
-
-	ch := make(chan os.Signal)
+	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
 	<-ch
-	messageConsumer.Stop()
-	consumerWaitGroup.Wait()
+	shutdown.Run(server)
+}
+
+// consumeMessage handles the message on the consumer goroutine, when the worker pool is disabled
+func consumeMessage(msg consumer.Message) {
+	inFlight.Add(1)
+	defer inFlight.Done()
+	if metadataPublishEvent, ok := unmarshalEvent(msg); ok {
+		handleEvent(msg, metadataPublishEvent)
+	}
+}
+
+// consumeBatch handles the messages of the batch on the worker pool, returning once they are all handled
+// so that the consumer only commits handled messages
+func consumeBatch(msgs []consumer.Message) {
+	inFlight.Add(1)
+	defer inFlight.Done()
+	var accepted []consumer.Message
+	var events []MetadataPublishEvent
+	for _, msg := range msgs {
+		if metadataPublishEvent, ok := unmarshalEvent(msg); ok {
+			accepted = append(accepted, msg)
+			events = append(events, metadataPublishEvent)
+		}
+	}
+	if err := workerPool.Handle(accepted, events); err != nil {
+		errorLogger.Printf("Error handling a batch of [%d] messages: [%v]", len(accepted), err.Error())
+	}
 }
 
-func handleMessage(msg consumer.Message) {
+// unmarshalEvent returns the metadata publish event of the message, unless the message is a duplicate,
+// is filtered or cannot be unmarshalled
+func unmarshalEvent(msg consumer.Message) (MetadataPublishEvent, bool) {
 	tid := msg.Headers["X-Request-Id"]
 
+	if dedupCache.isDuplicate(msg.Headers["Message-Id"]) {
+		skippedMessages.Add(skippedAsDuplicate, 1)
+		infoLogger.Printf("[%s] Skipping duplicate message with Message-Id [%s]", tid, msg.Headers["Message-Id"])
+		return MetadataPublishEvent{}, false
+	}
+
+	if accepted, reason := messageFilter.accept(msg.Headers); !accepted {
+		skippedMessages.Add(reason, 1)
+		debugLogger.Printf("[%s] Skipping message with Message-Type [%s] and Origin-System-Id [%s] filtered by %s", tid, msg.Headers["Message-Type"], msg.Headers["Origin-System-Id"], reason)
+		return MetadataPublishEvent{}, false
+	}
+	freshness.Received()
+
 	var metadataPublishEvent MetadataPublishEvent
 	err := json.Unmarshal([]byte(msg.Body), &metadataPublishEvent)
 	if err != nil {
 		errorLogger.Printf("[%s] Cannot unmarshal message body:[%v]", tid, err.Error())
-		return
+		errorRates.Failed(stageUnmarshalEvent)
+		return MetadataPublishEvent{}, false
 	}
+	return metadataPublishEvent, true
+}
+
+func handleEvent(msg consumer.Message, metadataPublishEvent MetadataPublishEvent) {
+	tid := msg.Headers["X-Request-Id"]
 
 	infoLogger.Printf("[%s] Processing metadata publish event for uuid [%s]", tid, metadataPublishEvent.UUID)
 
-	metadataXML, err := base64.StdEncoding.DecodeString(metadataPublishEvent.Value)
+	metadataValue, err := decodeValue(metadataPublishEvent)
+	if err == nil {
+		metadataValue, err = decompress(msg.Headers["Content-Encoding"], metadataValue)
+	}
 	if err != nil {
 		errorLogger.Printf("[%s] Error decoding body for uuid:  [%s]", tid, err.Error())
+		errorRates.Failed(stageDecodeBody)
 		return
 	}
 
-	metadata, err, hadInvalidChars := unmarshalMetadata(metadataXML)
+	format := metadataFormat(msg.Headers["Content-Type"], metadataValue)
+	metadata, err, hadInvalidChars := unmarshalContentRef(format, metadataValue)
+	var repairedOffsets []int
+	if err != nil && hadInvalidChars && utf8Repair != "" {
+		metadata, repairedOffsets, err = repairContentRef(format, metadataValue)
+		if err == nil {
+			warnLogger.Printf("[%s] Repaired invalid UTF8 characters in metadata %s for UUID [%s] with [%s] at byte offsets %v", tid, strings.ToUpper(format), metadataPublishEvent.UUID, utf8Repair, repairedOffsets)
+		}
+	}
 
 	if err != nil {
-		errorLogger.Printf("[%s] Error unmarshalling metadata XML for UUID [%v]: [%v]", tid, metadataPublishEvent.UUID, err.Error())
+		errorLogger.Printf("[%s] Error unmarshalling metadata %s for UUID [%v]: [%v]", tid, strings.ToUpper(format), metadataPublishEvent.UUID, err.Error())
 		if hadInvalidChars {
-			infoLogger.Printf("[%s] Metadata XML for UUID [%s] had invalid UTF8 characters.", tid, metadataPublishEvent.UUID)
+			infoLogger.Printf("[%s] Metadata %s for UUID [%s] had invalid UTF8 characters.", tid, strings.ToUpper(format), metadataPublishEvent.UUID)
 		}
+		errorRates.Failed(stageUnmarshalMetadata)
 		return
 	}
 
-	suggestions := []suggestion{}
-	for key, value := range taxonomyHandlers {
-		infoLogger.Printf("[%s] Processing taxonomy [%s]", tid, key)
-		suggestions = append(suggestions, value.buildSuggestions(metadata)...)
+	if staleEvents.isStale(metadataPublishEvent.UUID, metadata.Created) {
+		staleEvents.handleStaleEvent(tid, metadataPublishEvent.UUID, msg, staleEventsProducer)
+		freshness.Skipped()
+		return
 	}
 
-	conceptSuggestion := ConceptSuggestion{UUID: metadataPublishEvent.UUID, Suggestions: suggestions}
+	for _, t := range findBlankLabelTerms(metadata) {
+		blankLabelTerms.Add(1)
+		warnLogger.Printf("[%s] Skipping term [%s] of taxonomy [%s] for UUID [%s], its canonical name %q is blank once normalised", tid, t.ID, t.Taxonomy, metadataPublishEvent.UUID, t.CanonicalName)
+	}
+
+	conceptSuggestion := suggestConcepts(tid, metadataPublishEvent.UUID, msg.Headers, metadata)
 
-	marshalledSuggestions, err := json.Marshal(conceptSuggestion)
+	var headers = buildConceptSuggestionsHeader(msg.Headers, headerConfig)
+	if repairedOffsets != nil {
+		addRepairHeaders(headers, repairedOffsets)
+	}
+	message, err := primaryOutput.Message(conceptSuggestion, headers, msg.Headers)
 	if err != nil {
 		errorLogger.Printf("[%s] Error marshalling the concept suggestions for UUID [%v]: [%v]", tid, metadataPublishEvent.UUID, err.Error())
+		errorRates.Failed(stageMarshalSuggestions)
+		return
+	}
+	if err := primaryOutput.Validate(conceptSuggestion, msg.Headers); err != nil {
+		errorLogger.Printf("[%s] %v", tid, err.Error())
+		errorRates.Failed(stageValidateSchema)
+		sendToDeadLetter(tid, conceptSuggestion.UUID, message, err)
+		freshness.Skipped()
 		return
 	}
-
-	var headers = buildConceptSuggestionsHeader(msg.Headers)
-	message := producer.Message{Headers: headers, Body: string(marshalledSuggestions)}
 	err = messageProducer.SendMessage(conceptSuggestion.UUID, message)
 	if err != nil {
 		errorLogger.Printf("[%s] Error sending concept suggestion to queue for UUID [%v]: [%v]", tid, metadataPublishEvent.UUID, err.Error())
+		errorRates.Failed(stageSendMessage)
+		return
 	}
 
 	infoLogger.Printf("[%s] Sent suggestion message for [%s] with message ID [%s] to queue.", tid, metadataPublishEvent.UUID, headers["Message-Id"])
+	freshness.Processed(msg.Headers["Message-Timestamp"])
+	errorRates.Succeeded()
+	staleEvents.processed(metadataPublishEvent.UUID, metadata.Created)
+
+	sendDualWrite(tid, conceptSuggestion, headers, msg.Headers)
+	storeSuggestion(tid, conceptSuggestion, msg.Headers)
 }
 
-func unmarshalMetadata(metadataXML []byte) (ContentRef, error, bool) {
-	metadata := ContentRef{}
-	err := xml.Unmarshal(metadataXML, &metadata)
+// sendDualWrite also sends the concept suggestions in the dual-write schema version, when configured.
+// Failures are logged and counted, but don't fail the message.
+func sendDualWrite(tid string, conceptSuggestion ConceptSuggestion, headers map[string]string, sourceHeaders map[string]string) {
+	if dualWriteProducer == nil {
+		return
+	}
+	message, err := dualWriteOutput.Message(conceptSuggestion, headers, sourceHeaders)
+	if err == nil {
+		err = dualWriteOutput.Validate(conceptSuggestion, sourceHeaders)
+		if err != nil {
+			sendToDeadLetter(tid, conceptSuggestion.UUID, message, err)
+		}
+	}
 	if err == nil {
-		return metadata, nil, false
+		err = dualWriteProducer.SendMessage(conceptSuggestion.UUID, message)
+	}
+	if err != nil {
+		errorLogger.Printf("[%s] Error sending concept suggestions in schema version [%s] for UUID [%s]: [%v]", tid, dualWriteOutput.Version, conceptSuggestion.UUID, err.Error())
+		failedMessages.Add(stageDualWrite, 1)
+		return
+	}
+	infoLogger.Printf("[%s] Sent suggestion message for [%s] in schema version [%s] to the dual-write topic.", tid, conceptSuggestion.UUID, dualWriteOutput.Version)
+}
+
+// sendToDeadLetter sends the invalid concept suggestions message to the dead-letter topic, when configured
+func sendToDeadLetter(tid string, uuid string, message producer.Message, validationErr error) {
+	if deadLetterProducer == nil {
+		return
+	}
+	headers := map[string]string{"Validation-Error": validationErr.Error()}
+	for name, value := range message.Headers {
+		headers[name] = value
+	}
+	if err := deadLetterProducer.SendMessage(uuid, producer.Message{Headers: headers, Body: message.Body}); err != nil {
+		errorLogger.Printf("[%s] Error sending invalid concept suggestions for UUID [%s] to the dead-letter topic: [%v]", tid, uuid, err.Error())
+		return
+	}
+	infoLogger.Printf("[%s] Sent invalid concept suggestions for UUID [%s] to the dead-letter topic", tid, uuid)
+}
+
+func storeSuggestion(tid string, conceptSuggestion ConceptSuggestion, sourceHeaders map[string]string) {
+	if suggestionStore == nil {
+		return
+	}
+	record := SuggestionRecord{
+		ConceptSuggestion: conceptSuggestion,
+		Headers:           sourceHeaders,
+		ProcessedAt:       time.Now().UTC().Format(messageTimestampDateFormat),
+	}
+	if err := suggestionStore.Save(record); err != nil {
+		errorLogger.Printf("[%s] Error storing concept suggestion for UUID [%v]: [%v]", tid, conceptSuggestion.UUID, err.Error())
 	}
-	return metadata, err, !utf8.Valid(metadataXML)
 }
 
-func buildConceptSuggestionsHeader(publishEventHeaders map[string]string) map[string]string {
-	return map[string]string{
-		"Message-Id":        uuid.NewV4().String(),
-		"Message-Type":      "concept-suggestions",
-		"Content-Type":      publishEventHeaders["Content-Type"],
-		"X-Request-Id":      publishEventHeaders["X-Request-Id"],
-		"Origin-System-Id":  publishEventHeaders["Origin-System-Id"],
-		"Message-Timestamp": time.Now().Format(messageTimestampDateFormat),
+// reservedHeaders describe the source message body, so they are never passed through onto the
+// concept suggestions. Content-Encoding is only set when the suggestions are compressed.
+var reservedHeaders = map[string]bool{"Content-Type": true, "Content-Encoding": true}
+
+// HeaderConfig configures the lineage of the concept suggestion messages
+type HeaderConfig struct {
+	OriginSystemID string
+	PassThrough    []string
+}
+
+func buildConceptSuggestionsHeader(publishEventHeaders map[string]string, config HeaderConfig) map[string]string {
+	headers := map[string]string{
+		"Message-Id":               uuid.NewV4().String(),
+		"Message-Type":             "concept-suggestions",
+		"Content-Type":             publishEventHeaders["Content-Type"],
+		"X-Request-Id":             publishEventHeaders["X-Request-Id"],
+		"Origin-System-Id":         publishEventHeaders["Origin-System-Id"],
+		"Message-Timestamp":        time.Now().Format(messageTimestampDateFormat),
+		"Source-Message-Timestamp": publishEventHeaders["Message-Timestamp"],
+		"Causation-Id":             publishEventHeaders["Message-Id"],
+	}
+	if config.OriginSystemID != "" {
+		headers["Origin-System-Id"] = config.OriginSystemID
+	}
+	for _, name := range config.PassThrough {
+		value, found := publishEventHeaders[name]
+		canonicalName := http.CanonicalHeaderKey(name)
+		_, set := headers[canonicalName]
+		if found && !set && !reservedHeaders[canonicalName] {
+			headers[name] = value
+		}
 	}
+	return headers
 }
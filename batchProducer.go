@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Financial-Times/message-queue-go-producer/producer"
+)
+
+const kafkaBinaryContentType = "application/vnd.kafka.binary.v1+json"
+
+var errBatchProducerClosed = errors.New("Batch producer is closed, the message was not sent")
+
+// batchSize measures the number of messages published per request
+var batchSize = newHistogram("batchSize", []float64{1, 2, 5, 10, 20, 50, 100})
+
+// BatchProducer is a producer.MessageProducer publishing the messages sent within the linger time
+// in one request to the rest proxy, up to maxSize messages per request. The messages are published
+// in the order they were sent, keyed by UUID, and SendMessage returns the outcome for its own message.
+type BatchProducer struct {
+	config   producer.MessageProducerConfig
+	client   *http.Client
+	producer producer.MessageProducer
+	maxSize  int
+	linger   time.Duration
+	queue    chan batchedMessage
+	done     chan struct{}
+	// closed is set by Close, and read-locked while a message is queued
+	closed bool
+	lock   sync.RWMutex
+}
+
+type batchedMessage struct {
+	uuid    string
+	message producer.Message
+	result  chan error
+}
+
+type batchRecord struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type batchRequest struct {
+	Records []batchRecord `json:"records"`
+}
+
+type batchOffset struct {
+	Partition int    `json:"partition"`
+	Offset    int64  `json:"offset"`
+	ErrorCode *int   `json:"error_code"`
+	Error     string `json:"error"`
+}
+
+type batchResponse struct {
+	Offsets []batchOffset `json:"offsets"`
+}
+
+// validateBatchSize accepts batches of more than one message only with a pool of several workers,
+// otherwise each message would wait the whole linger time for a batch of its own
+func validateBatchSize(maxSize int, workerPoolSize int) error {
+	if maxSize > 1 && workerPoolSize < 2 {
+		return fmt.Errorf("Batches of [%d] messages need a worker pool of more than one worker, as each message waits for its batch to be published", maxSize)
+	}
+	return nil
+}
+
+// NewBatchProducer returns a batching producer for the given configuration, using p for the connectivity checks
+func NewBatchProducer(config producer.MessageProducerConfig, client *http.Client, p producer.MessageProducer, maxSize int, linger time.Duration) *BatchProducer {
+	b := &BatchProducer{
+		config:   config,
+		client:   client,
+		producer: p,
+		maxSize:  maxSize,
+		linger:   linger,
+		queue:    make(chan batchedMessage, maxSize),
+		done:     make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// SendMessage adds the message to the next batch and waits for the batch to be published.
+// It fails once the producer is closed.
+func (b *BatchProducer) SendMessage(uuid string, message producer.Message) error {
+	b.lock.RLock()
+	if b.closed {
+		b.lock.RUnlock()
+		return errBatchProducerClosed
+	}
+	result := make(chan error, 1)
+	b.queue <- batchedMessage{uuid: uuid, message: message, result: result}
+	b.lock.RUnlock()
+	return <-result
+}
+
+// ConnectivityCheck checks the connectivity of the producer
+func (b *BatchProducer) ConnectivityCheck() (string, error) {
+	return b.producer.ConnectivityCheck()
+}
+
+// Close publishes the pending messages, or returns the context error if the context is done first.
+// No message can be sent afterwards.
+func (b *BatchProducer) Close(ctx context.Context) error {
+	b.lock.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+	}
+	b.lock.Unlock()
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *BatchProducer) run() {
+	defer close(b.done)
+	for {
+		first, ok := <-b.queue
+		if !ok {
+			return
+		}
+		batch := []batchedMessage{first}
+		timer := time.NewTimer(b.linger)
+	collect:
+		for len(batch) < b.maxSize {
+			select {
+			case next, ok := <-b.queue:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, next)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		errs := b.publish(batch)
+		for i, m := range batch {
+			m.result <- errs[i]
+		}
+	}
+}
+
+// publish sends the batch in one request, returning the error for each message
+func (b *BatchProducer) publish(batch []batchedMessage) []error {
+	batchSize.Observe(float64(len(batch)))
+	errs := make([]error, len(batch))
+	offsets, err := b.post(batch)
+	for i := range batch {
+		switch {
+		case err != nil:
+			errs[i] = err
+		case i >= len(offsets):
+			errs[i] = fmt.Errorf("No offset returned for message %d of the batch", i)
+		case offsets[i].Error != "" || offsets[i].ErrorCode != nil:
+			errs[i] = fmt.Errorf("Error publishing message %d of the batch: %s", i, offsets[i].Error)
+		}
+	}
+	return errs
+}
+
+func (b *BatchProducer) post(batch []batchedMessage) ([]batchOffset, error) {
+	request := batchRequest{}
+	for _, m := range batch {
+		request.Records = append(request.Records, batchRecord{
+			Key:   base64.StdEncoding.EncodeToString([]byte(m.uuid)),
+			Value: base64.StdEncoding.EncodeToString([]byte(buildFTMessage(m.message))),
+		})
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", b.config.Addr+"/topics/"+b.config.Topic, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", kafkaBinaryContentType)
+	if b.config.Queue != "" {
+		req.Host = b.config.Queue
+	}
+	if b.config.Authorization != "" {
+		req.Header.Set("Authorization", b.config.Authorization)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected response status %d publishing a batch of %d messages: %s", resp.StatusCode, len(batch), respBody)
+	}
+
+	var response batchResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return nil, err
+	}
+	return response.Offsets, nil
+}
+
+// buildFTMessage builds the FTMSG/1.0 message as the queue producer does, with the headers sorted by name
+func buildFTMessage(message producer.Message) string {
+	var keys []string
+	for k := range message.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	b.WriteString("FTMSG/1.0\n")
+	for _, k := range keys {
+		b.WriteString(k + ": " + message.Headers[k] + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(message.Body)
+	return b.String()
+}
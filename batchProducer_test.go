@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/message-queue-go-producer/producer"
+	"github.com/stretchr/testify/assert"
+)
+
+type restProxy struct {
+	batches [][]batchRecord
+	failing map[int]bool
+	status  int
+	lock    sync.Mutex
+}
+
+func (p *restProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.status != 0 {
+		w.WriteHeader(p.status)
+		return
+	}
+	var request batchRequest
+	json.NewDecoder(r.Body).Decode(&request)
+	p.batches = append(p.batches, request.Records)
+
+	response := batchResponse{}
+	for i := range request.Records {
+		offset := batchOffset{Offset: int64(i)}
+		if p.failing[i] {
+			code := 50002
+			offset.ErrorCode = &code
+			offset.Error = "Kafka error"
+		}
+		response.Offsets = append(response.Offsets, offset)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func newTestBatchProducer(proxy *restProxy, maxSize int) (*BatchProducer, func()) {
+	server := httptest.NewServer(proxy)
+	config := producer.MessageProducerConfig{Addr: server.URL, Topic: "ConceptSuggestions"}
+	return NewBatchProducer(config, http.DefaultClient, &mockProducerInstance{isConnectionHealthy: true}, maxSize, 50*time.Millisecond), server.Close
+}
+
+func sendConcurrently(b *BatchProducer, count int) []error {
+	errs := make([]error, count)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = b.SendMessage(fmt.Sprintf("uuid-%d", i), producer.Message{Headers: map[string]string{"Message-Id": fmt.Sprint(i)}, Body: "{}"})
+		}(i)
+		time.Sleep(time.Millisecond)
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestBatchProducerPublishesBatches(t *testing.T) {
+	proxy := &restProxy{}
+	b, stop := newTestBatchProducer(proxy, 3)
+	defer stop()
+
+	for _, err := range sendConcurrently(b, 5) {
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, b.Close(context.Background()))
+
+	assert.Len(t, proxy.batches, 2)
+	assert.Len(t, proxy.batches[0], 3)
+	assert.Len(t, proxy.batches[1], 2)
+	key, _ := base64.StdEncoding.DecodeString(proxy.batches[0][0].Key)
+	assert.Equal(t, "uuid-0", string(key), "Messages should be published in order")
+	value, _ := base64.StdEncoding.DecodeString(proxy.batches[0][0].Value)
+	assert.Equal(t, "FTMSG/1.0\nMessage-Id: 0\n\n{}", string(value))
+}
+
+func TestBatchProducerReportsPartialFailures(t *testing.T) {
+	proxy := &restProxy{failing: map[int]bool{1: true}}
+	b, stop := newTestBatchProducer(proxy, 3)
+	defer stop()
+
+	errs := sendConcurrently(b, 3)
+	assert.NoError(t, errs[0])
+	assert.EqualError(t, errs[1], "Error publishing message 1 of the batch: Kafka error")
+	assert.NoError(t, errs[2])
+}
+
+func TestBatchProducerFailsWholeBatch(t *testing.T) {
+	proxy := &restProxy{status: http.StatusServiceUnavailable}
+	b, stop := newTestBatchProducer(proxy, 3)
+	defer stop()
+
+	for _, err := range sendConcurrently(b, 2) {
+		assert.Error(t, err)
+	}
+}
+
+func TestBatchProducerFailsAfterClose(t *testing.T) {
+	proxy := &restProxy{}
+	b, stop := newTestBatchProducer(proxy, 3)
+	defer stop()
+
+	assert.NoError(t, b.Close(context.Background()))
+	assert.NoError(t, b.Close(context.Background()), "Closing again should do nothing")
+	assert.Equal(t, errBatchProducerClosed, b.SendMessage("uuid", producer.Message{}))
+	assert.Empty(t, proxy.batches)
+}
+
+func TestValidateBatchSize(t *testing.T) {
+	assert.NoError(t, validateBatchSize(0, 0))
+	assert.NoError(t, validateBatchSize(1, 0))
+	assert.NoError(t, validateBatchSize(10, 8))
+	assert.EqualError(t, validateBatchSize(10, 1), "Batches of [10] messages need a worker pool of more than one worker, as each message waits for its batch to be published")
+	assert.Error(t, validateBatchSize(10, 0))
+}
+
+type capturedRequest struct {
+	method  string
+	path    string
+	host    string
+	headers http.Header
+	records []batchRecord
+}
+
+// captureRequest returns the request the send function made to the rest proxy
+func captureRequest(t *testing.T, send func(config producer.MessageProducerConfig) error) capturedRequest {
+	var captured capturedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request batchRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		captured = capturedRequest{method: r.Method, path: r.URL.Path, host: r.Host, headers: http.Header{}, records: request.Records}
+		for _, header := range []string{"Content-Type", "Authorization"} {
+			captured.headers.Set(header, r.Header.Get(header))
+		}
+		json.NewEncoder(w).Encode(batchResponse{Offsets: []batchOffset{{}}})
+	}))
+	defer server.Close()
+
+	config := producer.MessageProducerConfig{Addr: server.URL, Topic: "ConceptSuggestions", Queue: "kafka", Authorization: "Basic c2VjcmV0"}
+	assert.NoError(t, send(config))
+	return captured
+}
+
+func TestBatchProducerPublishesLikeTheQueueProducer(t *testing.T) {
+	message := producer.Message{
+		Headers: map[string]string{"X-Request-Id": "tid_test", "Message-Id": "a2b3", "Content-Type": "application/json", "Origin-System-Id": "methode-web-pub"},
+		Body:    `{"uuid":"c4d5","suggestions":[]}`,
+	}
+
+	expected := captureRequest(t, func(config producer.MessageProducerConfig) error {
+		return producer.NewMessageProducerWithHTTPClient(config, http.DefaultClient).SendMessage("c4d5", message)
+	})
+	actual := captureRequest(t, func(config producer.MessageProducerConfig) error {
+		b := NewBatchProducer(config, http.DefaultClient, &mockProducerInstance{isConnectionHealthy: true}, 2, time.Millisecond)
+		defer b.Close(context.Background())
+		return b.SendMessage("c4d5", message)
+	})
+
+	assert.Equal(t, expected, actual)
+	if assert.Len(t, actual.records, 1) {
+		value, _ := base64.StdEncoding.DecodeString(actual.records[0].Value)
+		assert.Equal(t, buildFTMessage(message), string(value))
+	}
+}
@@ -10,7 +10,9 @@ func (brandService BrandService) buildSuggestions(contentRef ContentRef) []sugge
 	authors := extractTags(brandService.HandledTaxonomy, contentRef)
 	suggestions := []suggestion{}
 	for _, value := range authors {
-		suggestions = append(suggestions, buildSuggestion(value, brandURI, classification))
+		if suggestion, labelled := buildSuggestion(value, brandURI, classification); labelled {
+			suggestions = append(suggestions, suggestion)
+		}
 	}
 	return suggestions
 }
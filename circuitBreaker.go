@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Financial-Times/message-queue-go-producer/producer"
+)
+
+// CircuitBreaker is a producer.MessageProducer which stops sending messages after consecutive failures,
+// until the producer connectivity check succeeds again
+type CircuitBreaker struct {
+	producer      producer.MessageProducer
+	threshold     int
+	probeInterval time.Duration
+	failures      int
+	openedAt      time.Time
+	// closed is closed when the open breaker closes again
+	closed chan struct{}
+	// OnOpen and OnClose are called when the breaker opens and closes, in order on one goroutine.
+	// Transitions happening while a callback runs are coalesced into the current state.
+	OnOpen  func()
+	OnClose func()
+	changed chan struct{}
+	lock    sync.Mutex
+}
+
+// NewCircuitBreaker returns a breaker opening after threshold consecutive failures, and probing the producer
+// connectivity every probeInterval while open. The breaker never opens when threshold is 0.
+func NewCircuitBreaker(p producer.MessageProducer, threshold int, probeInterval time.Duration) *CircuitBreaker {
+	b := &CircuitBreaker{
+		producer:      p,
+		threshold:     threshold,
+		probeInterval: probeInterval,
+		OnOpen:        func() {},
+		OnClose:       func() {},
+		changed:       make(chan struct{}, 1),
+	}
+	go b.callOnChange()
+	return b
+}
+
+// SendMessage sends the message, waiting while the breaker is open. A message failing while the breaker
+// is open, or opening it, is sent again once the breaker closes, so that it is never acknowledged unsent.
+func (b *CircuitBreaker) SendMessage(uuid string, message producer.Message) error {
+	for {
+		b.waitUntilClosed()
+		err := b.producer.SendMessage(uuid, message)
+		if !b.record(err) {
+			return err
+		}
+		warnLogger.Printf("Error sending message for UUID [%s] while the circuit breaker is open, sending it again once closed: [%v]", uuid, err.Error())
+	}
+}
+
+func (b *CircuitBreaker) waitUntilClosed() {
+	b.lock.Lock()
+	closed := b.closed
+	b.lock.Unlock()
+	if closed != nil {
+		<-closed
+	}
+}
+
+// ConnectivityCheck checks the connectivity of the producer
+func (b *CircuitBreaker) ConnectivityCheck() (string, error) {
+	return b.producer.ConnectivityCheck()
+}
+
+// IsOpen returns whether messages are currently not being sent
+func (b *CircuitBreaker) IsOpen() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return !b.openedAt.IsZero()
+}
+
+// record counts the consecutive failures, and returns whether the failed message should be sent again
+// because the breaker is open
+func (b *CircuitBreaker) record(err error) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if err == nil {
+		b.failures = 0
+		return false
+	}
+	b.failures++
+	if !b.openedAt.IsZero() {
+		return true
+	}
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return false
+	}
+	b.openedAt = time.Now()
+	b.closed = make(chan struct{})
+	warnLogger.Printf("Circuit breaker opened after [%d] consecutive failures to send messages", b.failures)
+	b.notifyChange()
+	go b.probe()
+	return true
+}
+
+// probe closes the breaker once the producer connectivity check succeeds
+func (b *CircuitBreaker) probe() {
+	ticker := time.NewTicker(b.probeInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := b.producer.ConnectivityCheck(); err != nil {
+			continue
+		}
+		b.lock.Lock()
+		b.openedAt = time.Time{}
+		b.failures = 0
+		close(b.closed)
+		b.closed = nil
+		b.lock.Unlock()
+		infoLogger.Printf("Circuit breaker closed, the write queue is reachable again")
+		b.notifyChange()
+		return
+	}
+}
+
+// notifyChange wakes callOnChange up, without blocking when it is already due to run
+func (b *CircuitBreaker) notifyChange() {
+	select {
+	case b.changed <- struct{}{}:
+	default:
+	}
+}
+
+// callOnChange calls OnOpen or OnClose whenever the state of the breaker differs from the one last
+// notified, so that the callbacks always alternate and leave the consumer in the current state
+func (b *CircuitBreaker) callOnChange() {
+	notifiedOpen := false
+	for range b.changed {
+		if open := b.IsOpen(); open != notifiedOpen {
+			notifiedOpen = open
+			if open {
+				b.OnOpen()
+			} else {
+				b.OnClose()
+			}
+		}
+	}
+}
+
+// Check fails while the breaker is open
+func (b *CircuitBreaker) Check() (string, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.openedAt.IsZero() {
+		return fmt.Sprintf("Circuit breaker is closed, %d consecutive failures to send messages", b.failures), nil
+	}
+	return "", fmt.Errorf("Circuit breaker opened at [%s] after %d consecutive failures to send messages, consumption is paused until the write queue is reachable", formatTimestamp(b.openedAt), b.failures)
+}
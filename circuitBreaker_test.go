@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/message-queue-go-producer/producer"
+	"github.com/stretchr/testify/assert"
+)
+
+type flakyProducer struct {
+	healthy   bool
+	sent      int
+	delivered []string
+	lock      sync.Mutex
+}
+
+func (p *flakyProducer) SendMessage(uuid string, _ producer.Message) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.sent++
+	if !p.healthy {
+		return errors.New("Error sending the message")
+	}
+	p.delivered = append(p.delivered, uuid)
+	return nil
+}
+
+func (p *flakyProducer) ConnectivityCheck() (string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if !p.healthy {
+		return "", errors.New("Error connecting to the queue")
+	}
+	return "", nil
+}
+
+func (p *flakyProducer) setHealthy(healthy bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.healthy = healthy
+}
+
+func TestCircuitBreakerOpensAndCloses(t *testing.T) {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	p := &flakyProducer{}
+	b := NewCircuitBreaker(p, 3, time.Millisecond)
+	opened := make(chan struct{})
+	closed := make(chan struct{})
+	b.OnOpen = func() { close(opened) }
+	b.OnClose = func() { close(closed) }
+
+	for i := 0; i < 2; i++ {
+		assert.Error(t, b.SendMessage("failed", producer.Message{}), "Failures below the threshold are returned")
+	}
+	results := make(chan error)
+	go func() { results <- b.SendMessage("opening", producer.Message{}) }()
+	<-opened
+	assert.True(t, b.IsOpen())
+	_, err := b.Check()
+	assert.Error(t, err)
+
+	go func() { results <- b.SendMessage("while-open", producer.Message{}) }()
+	select {
+	case err := <-results:
+		t.Fatalf("Sending should wait while the breaker is open, got [%v]", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	p.setHealthy(true)
+	<-closed
+	assert.NoError(t, <-results)
+	assert.NoError(t, <-results)
+	assert.False(t, b.IsOpen())
+	sort.Strings(p.delivered)
+	assert.Equal(t, []string{"opening", "while-open"}, p.delivered, "Messages sent while open should be delivered once closed")
+	_, err = b.Check()
+	assert.NoError(t, err)
+}
+
+func TestCircuitBreakerCallsBackInOrder(t *testing.T) {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	p := &flakyProducer{}
+	b := NewCircuitBreaker(p, 1, time.Millisecond)
+	var calls []string
+	opening := make(chan struct{})
+	release := make(chan struct{})
+	closed := make(chan struct{})
+	b.OnOpen = func() {
+		close(opening)
+		<-release
+		calls = append(calls, "open")
+	}
+	b.OnClose = func() {
+		calls = append(calls, "close")
+		close(closed)
+	}
+
+	go b.SendMessage("uuid", producer.Message{})
+	<-opening
+	p.setHealthy(true)
+	for b.IsOpen() {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	<-closed
+
+	assert.Equal(t, []string{"open", "close"}, calls, "The breaker closing while suspending should resume afterwards")
+}
+
+func TestCircuitBreakerResetsOnSuccess(t *testing.T) {
+	p := &flakyProducer{}
+	b := NewCircuitBreaker(p, 2, time.Millisecond)
+
+	b.SendMessage("uuid", producer.Message{})
+	p.setHealthy(true)
+	b.SendMessage("uuid", producer.Message{})
+	p.setHealthy(false)
+	b.SendMessage("uuid", producer.Message{})
+
+	assert.False(t, b.IsOpen(), "Failures are not consecutive")
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := NewCircuitBreaker(&flakyProducer{}, 0, time.Millisecond)
+	for i := 0; i < 10; i++ {
+		b.SendMessage("uuid", producer.Message{})
+	}
+	assert.False(t, b.IsOpen())
+}
+
+func TestCircuitBreakerSuspendsConsumption(t *testing.T) {
+	f := &consumerFactory{}
+	c := NewPausableConsumer(f.newConsumer, "")
+	finished := make(chan struct{})
+	go func() {
+		c.Start()
+		close(finished)
+	}()
+	<-f.consumer(0).started
+
+	c.Suspend()
+	assert.NoError(t, c.Pause())
+	c.Unsuspend()
+	_, err := c.PausedCheck()
+	assert.Error(t, err, "Should stay paused when the breaker closes")
+
+	assert.NoError(t, c.Resume())
+	<-f.consumer(1).started
+
+	c.Stop()
+	<-finished
+}
+
+func TestHealthCheckWithCircuitBreaker(t *testing.T) {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	b := NewCircuitBreaker(&flakyProducer{}, 1, time.Hour)
+	b.record(errors.New("Error sending the message"))
+	hc := initializeHealthCheck(true, true)
+	hc.addCheck(circuitBreakerCheck(b))
+
+	w := httptest.NewRecorder()
+	hc.Health()(w, httptest.NewRequest("GET", "http://example.com/__health", nil))
+
+	assert.Contains(t, w.Body.String(), `"name":"Write Queue Circuit Breaker","ok":false`)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/Financial-Times/message-queue-go-producer/producer"
+	"github.com/klauspost/compress/zstd"
+)
+
+// The Content-Encoding of compressed message bodies
+const (
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+)
+
+// maxDecompressedBytes bounds the size of decompressed metadata, against decompression bombs
+const maxDecompressedBytes = 64 << 20
+
+var sizeBounds = []float64{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20}
+
+// The sizes in bytes of the source metadata as received, possibly compressed, and once decompressed
+var sourceValueSize = newHistogram("sourceValueBytes", sizeBounds)
+var sourceMetadataSize = newHistogram("sourceMetadataBytes", sizeBounds)
+
+// The sizes in bytes of the concept suggestion bodies as rendered, and as sent, possibly compressed
+var suggestionSize = newHistogram("suggestionBytes", sizeBounds)
+var sentSuggestionSize = newHistogram("sentSuggestionBytes", sizeBounds)
+
+// zstd decoders and encoders are safe for concurrent use of DecodeAll and EncodeAll
+var zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxDecompressedBytes))
+var zstdEncoder, _ = zstd.NewWriter(nil)
+
+func validateCompression(encoding string) error {
+	switch encoding {
+	case "", compressionGzip, compressionZstd:
+		return nil
+	default:
+		return fmt.Errorf("Unknown compression [%s], expected %s or %s", encoding, compressionGzip, compressionZstd)
+	}
+}
+
+// decompress returns the data decompressed according to its Content-Encoding, unchanged when empty or identity
+func decompress(encoding string, data []byte) ([]byte, error) {
+	sourceValueSize.Observe(float64(len(data)))
+	var decompressed []byte
+	var err error
+	switch encoding {
+	case "", "identity":
+		decompressed = data
+	case compressionGzip:
+		decompressed, err = gunzip(data)
+	case compressionZstd:
+		decompressed, err = zstdDecoder.DecodeAll(data, nil)
+	default:
+		err = validateCompression(encoding)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sourceMetadataSize.Observe(float64(len(decompressed)))
+	return decompressed, nil
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	decompressed, err := ioutil.ReadAll(io.LimitReader(r, maxDecompressedBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decompressed) > maxDecompressedBytes {
+		return nil, fmt.Errorf("Decompressed gzip is larger than %d bytes", maxDecompressedBytes)
+	}
+	return decompressed, nil
+}
+
+// compress returns the data compressed with the encoding, unchanged when empty
+func compress(encoding string, data []byte) ([]byte, error) {
+	switch encoding {
+	case "":
+		return data, nil
+	case compressionGzip:
+		var b bytes.Buffer
+		w := gzip.NewWriter(&b)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	case compressionZstd:
+		return zstdEncoder.EncodeAll(data, nil), nil
+	default:
+		return nil, validateCompression(encoding)
+	}
+}
+
+// CompressingProducer is a producer.MessageProducer compressing the message bodies,
+// and setting their Content-Encoding
+type CompressingProducer struct {
+	producer producer.MessageProducer
+	encoding string
+}
+
+// NewCompressingProducer returns a producer sending messages through p, compressed with the encoding
+// when it is not empty
+func NewCompressingProducer(p producer.MessageProducer, encoding string) (*CompressingProducer, error) {
+	if err := validateCompression(encoding); err != nil {
+		return nil, err
+	}
+	return &CompressingProducer{producer: p, encoding: encoding}, nil
+}
+
+// SendMessage compresses the body of the message and sends it
+func (c *CompressingProducer) SendMessage(uuid string, message producer.Message) error {
+	suggestionSize.Observe(float64(len(message.Body)))
+	if c.encoding != "" {
+		body, err := compress(c.encoding, []byte(message.Body))
+		if err != nil {
+			return err
+		}
+		headers := map[string]string{}
+		for name, value := range message.Headers {
+			headers[name] = value
+		}
+		headers["Content-Encoding"] = c.encoding
+		message = producer.Message{Headers: headers, Body: string(body)}
+	}
+	sentSuggestionSize.Observe(float64(len(message.Body)))
+	return c.producer.SendMessage(uuid, message)
+}
+
+// ConnectivityCheck checks the connectivity of the producer
+func (c *CompressingProducer) ConnectivityCheck() (string, error) {
+	return c.producer.ConnectivityCheck()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/Financial-Times/message-queue-go-producer/producer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressionRoundTrip(t *testing.T) {
+	for _, encoding := range []string{"", compressionGzip, compressionZstd} {
+		compressed, err := compress(encoding, []byte(readmeSampleMetadata))
+		assert.NoError(t, err, encoding)
+		if encoding != "" {
+			assert.True(t, len(compressed) < len(readmeSampleMetadata), "%s should compress the metadata", encoding)
+		}
+
+		decompressed, err := decompress(encoding, compressed)
+		assert.NoError(t, err, encoding)
+		assert.Equal(t, readmeSampleMetadata, string(decompressed), encoding)
+	}
+}
+
+func TestDecompressErrors(t *testing.T) {
+	_, err := decompress("br", []byte("abc"))
+	assert.EqualError(t, err, "Unknown compression [br], expected gzip or zstd")
+
+	_, err = decompress(compressionGzip, []byte("not gzip"))
+	assert.Error(t, err)
+
+	_, err = decompress(compressionZstd, []byte("not zstd"))
+	assert.Error(t, err)
+}
+
+func TestGunzipIsBounded(t *testing.T) {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	w.Write(make([]byte, maxDecompressedBytes+1))
+	w.Close()
+
+	_, err := decompress(compressionGzip, b.Bytes())
+	assert.Error(t, err)
+}
+
+func TestCompressingProducer(t *testing.T) {
+	sent := &recordingProducer{}
+	p, err := NewCompressingProducer(sent, compressionGzip)
+	assert.NoError(t, err)
+	before := sentSuggestionSize.count
+
+	body := `{"uuid":"8bd0194e-e501-11e5-9ef8-8db78aefa51e","suggestions":[]}`
+	headers := map[string]string{"Content-Type": "application/json"}
+	assert.NoError(t, p.SendMessage("8bd0194e-e501-11e5-9ef8-8db78aefa51e", producer.Message{Headers: headers, Body: body}))
+
+	assert.Len(t, sent.messages, 1)
+	assert.Equal(t, "gzip", sent.messages[0].Headers["Content-Encoding"])
+	assert.Equal(t, "application/json", sent.messages[0].Headers["Content-Type"])
+	assert.NotContains(t, headers, "Content-Encoding", "The message headers should not be modified")
+	decompressed, err := decompress(compressionGzip, []byte(sent.messages[0].Body))
+	assert.NoError(t, err)
+	assert.Equal(t, body, string(decompressed))
+	assert.Equal(t, before+1, sentSuggestionSize.count)
+
+	_, err = NewCompressingProducer(sent, "lz4")
+	assert.Error(t, err)
+}
+
+func TestUncompressedProducer(t *testing.T) {
+	sent := &recordingProducer{}
+	p, _ := NewCompressingProducer(sent, "")
+	message := producer.Message{Headers: map[string]string{"Message-Id": "a2b3"}, Body: "{}"}
+
+	assert.NoError(t, p.SendMessage("a2b3", message))
+	assert.Equal(t, []producer.Message{message}, sent.messages)
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// conceptSuggestionSchemaV1 documents the JSON of ConceptSuggestion. It must be kept in sync
+// with the types in conceptSuggestion.go, and gets a new version when they change incompatibly.
+const conceptSuggestionSchemaV1 = `{
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "title": "ConceptSuggestion",
+  "description": "The concepts suggested for a piece of content, as sent on the ConceptSuggestions topic. Version 1.",
+  "type": "object",
+  "required": ["uuid", "suggestions"],
+  "additionalProperties": false,
+  "properties": {
+    "uuid": {
+      "type": "string",
+      "pattern": "^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$"
+    },
+    "suggestions": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["thing"],
+        "additionalProperties": false,
+        "properties": {
+          "thing": {
+            "type": "object",
+            "required": ["id", "prefLabel", "predicate", "types"],
+            "additionalProperties": false,
+            "properties": {
+              "id": {"type": "string", "pattern": "^https?://"},
+              "prefLabel": {"type": "string", "minLength": 1},
+              "predicate": {"type": "string", "minLength": 1},
+              "types": {"type": "array", "minItems": 1, "items": {"type": "string", "pattern": "^https?://"}}
+            }
+          },
+          "provenances": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["scores"],
+              "additionalProperties": false,
+              "properties": {
+                "scores": {
+                  "type": "array",
+                  "items": {
+                    "type": "object",
+                    "required": ["scoringSystem", "value"],
+                    "additionalProperties": false,
+                    "properties": {
+                      "scoringSystem": {"type": "string", "pattern": "^https?://"},
+                      "value": {"type": "number"}
+                    }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}
+`
+
+// conceptSuggestionSchemaV2 documents the JSON of ConceptSuggestionV2
+const conceptSuggestionSchemaV2 = `{
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "title": "ConceptSuggestion",
+  "description": "The concepts suggested for a piece of content, as sent on the ConceptSuggestions topic. Version 2.",
+  "type": "object",
+  "required": ["uuid", "annotations"],
+  "additionalProperties": false,
+  "properties": {
+    "uuid": {
+      "type": "string",
+      "pattern": "^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$"
+    },
+    "annotations": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["predicate", "concept"],
+        "additionalProperties": false,
+        "properties": {
+          "predicate": {"type": "string", "pattern": "^https?://"},
+          "concept": {
+            "type": "object",
+            "required": ["id", "prefLabel", "types"],
+            "additionalProperties": false,
+            "properties": {
+              "id": {"type": "string", "pattern": "^https?://"},
+              "prefLabel": {"type": "string", "minLength": 1},
+              "types": {"type": "array", "minItems": 1, "items": {"type": "string", "pattern": "^https?://"}}
+            }
+          },
+          "provenances": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["scores"],
+              "additionalProperties": false,
+              "properties": {
+                "atTime": {"type": "string", "pattern": "^[0-9]{4}-[0-9]{2}-[0-9]{2}T"},
+                "scores": {
+                  "type": "array",
+                  "items": {
+                    "type": "object",
+                    "required": ["scoringSystem", "value"],
+                    "additionalProperties": false,
+                    "properties": {
+                      "scoringSystem": {"type": "string", "pattern": "^https?://"},
+                      "value": {"type": "number"}
+                    }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}
+`
+
+// conceptSuggestionSchemas are the JSON schemas of ConceptSuggestion by version
+var conceptSuggestionSchemas = map[string]string{
+	"1": conceptSuggestionSchemaV1,
+	"2": conceptSuggestionSchemaV2,
+}
+
+// jsonSchema is the subset of JSON schema used by the ConceptSuggestion schemas
+type jsonSchema struct {
+	Type                 string                 `json:"type"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Items                *jsonSchema            `json:"items"`
+	MinItems             int                    `json:"minItems"`
+	MinLength            int                    `json:"minLength"`
+	Pattern              string                 `json:"pattern"`
+	pattern              *regexp.Regexp
+}
+
+func parseJSONSchema(schema string) (*jsonSchema, error) {
+	var s jsonSchema
+	if err := json.Unmarshal([]byte(schema), &s); err != nil {
+		return nil, err
+	}
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *jsonSchema) compile() error {
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return err
+		}
+		s.pattern = pattern
+	}
+	for _, property := range s.Properties {
+		if err := property.compile(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile()
+	}
+	return nil
+}
+
+// validate checks the decoded JSON value against the schema, returning the first violation found
+func (s *jsonSchema) validate(path string, value interface{}) error {
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s should be an object", path)
+		}
+		for _, name := range s.Required {
+			if _, found := object[name]; !found {
+				return fmt.Errorf("%s.%s is required", path, name)
+			}
+		}
+		var names []string
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, found := s.Properties[name]
+			if !found {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s.%s is not allowed", path, name)
+				}
+				continue
+			}
+			if err := property.validate(path+"."+name, object[name]); err != nil {
+				return err
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s should be an array", path)
+		}
+		if len(array) < s.MinItems {
+			return fmt.Errorf("%s should have at least %d items", path, s.MinItems)
+		}
+		for i, item := range array {
+			if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s should be a string", path)
+		}
+		if len(str) < s.MinLength {
+			return fmt.Errorf("%s should have at least %d characters", path, s.MinLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(str) {
+			return fmt.Errorf("%s [%s] should match %s", path, str, s.Pattern)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s should be a number", path)
+		}
+	}
+	return nil
+}
+
+// SchemaValidator validates the concept suggestions against a version of the schema
+type SchemaValidator struct {
+	version string
+	schema  *jsonSchema
+}
+
+// NewSchemaValidator returns a validator for the given version of the ConceptSuggestion schema
+func NewSchemaValidator(version string) (*SchemaValidator, error) {
+	schema, found := conceptSuggestionSchemas[version]
+	if !found {
+		return nil, fmt.Errorf("Unknown concept suggestion schema version [%s]", version)
+	}
+	parsed, err := parseJSONSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	return &SchemaValidator{version: version, schema: parsed}, nil
+}
+
+// Validate checks the JSON of the concept suggestions, in the version of the schema, against the schema
+func (v *SchemaValidator) Validate(conceptSuggestion interface{}) error {
+	data, err := json.Marshal(conceptSuggestion)
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if err := v.schema.validate("$", value); err != nil {
+		return fmt.Errorf("Invalid concept suggestions for schema version %s: %v", v.version, err)
+	}
+	return nil
+}
+
+// GetSchema writes the version of the ConceptSuggestion schema in the path
+func GetSchema(w http.ResponseWriter, r *http.Request) {
+	version := strings.TrimPrefix(mux.Vars(r)["version"], "v")
+	schema, found := conceptSuggestionSchemas[version]
+	if !found {
+		writeJSONMessage(w, http.StatusNotFound, "Unknown schema version")
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(schema))
+}
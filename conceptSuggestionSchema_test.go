@@ -0,0 +1,114 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Financial-Times/message-queue-go-producer/producer"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// assertSchemaMatchesType fails when the properties, types or required properties of the schema
+// drift from the JSON encoding of the Go type
+func assertSchemaMatchesType(t *testing.T, path string, schema *jsonSchema, typ reflect.Type) {
+	switch typ.Kind() {
+	case reflect.Struct:
+		if !assert.Equal(t, "object", schema.Type, path) {
+			return
+		}
+		var fields, required []string
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			tag := strings.Split(field.Tag.Get("json"), ",")
+			name := tag[0]
+			fields = append(fields, name)
+			if len(tag) == 1 || tag[1] != "omitempty" {
+				required = append(required, name)
+			}
+			property, found := schema.Properties[name]
+			if assert.True(t, found, "%s.%s is missing from the schema", path, name) {
+				assertSchemaMatchesType(t, path+"."+name, property, field.Type)
+			}
+		}
+		assert.Len(t, schema.Properties, len(fields), "%s has properties which are not in %v", path, typ)
+		sort.Strings(required)
+		schemaRequired := append([]string{}, schema.Required...)
+		sort.Strings(schemaRequired)
+		assert.Equal(t, required, schemaRequired, "%s required properties", path)
+		assert.False(t, *schema.AdditionalProperties, "%s should not allow additional properties", path)
+	case reflect.Slice:
+		if assert.Equal(t, "array", schema.Type, path) {
+			assertSchemaMatchesType(t, path+"[]", schema.Items, typ.Elem())
+		}
+	case reflect.String:
+		assert.Equal(t, "string", schema.Type, path)
+	case reflect.Float32, reflect.Float64:
+		assert.Equal(t, "number", schema.Type, path)
+	default:
+		t.Errorf("%s has a type %v which is not supported by the schema", path, typ)
+	}
+}
+
+func TestConceptSuggestionSchemaMatchesTypes(t *testing.T) {
+	schema, err := parseJSONSchema(conceptSuggestionSchemaV1)
+	assert.NoError(t, err)
+	assertSchemaMatchesType(t, "$", schema, reflect.TypeOf(ConceptSuggestion{}))
+
+	schema, err = parseJSONSchema(conceptSuggestionSchemaV2)
+	assert.NoError(t, err)
+	assertSchemaMatchesType(t, "$", schema, reflect.TypeOf(ConceptSuggestionV2{}))
+}
+
+func TestSchemaValidation(t *testing.T) {
+	validator, err := NewSchemaValidator("1")
+	assert.NoError(t, err)
+	assert.NoError(t, validator.Validate(buildTestConceptSuggestion()))
+
+	noLabel := buildTestConceptSuggestion()
+	noLabel.Suggestions[1].Thing.PrefLabel = ""
+	assert.EqualError(t, validator.Validate(noLabel), "Invalid concept suggestions for schema version 1: $.suggestions[1].thing.prefLabel should have at least 1 characters")
+
+	noTypes := buildTestConceptSuggestion()
+	noTypes.Suggestions[0].Thing.Types = nil
+	assert.EqualError(t, validator.Validate(noTypes), "Invalid concept suggestions for schema version 1: $.suggestions[0].thing.types should be an array")
+
+	invalidUUID := buildTestConceptSuggestion()
+	invalidUUID.UUID = "1234"
+	assert.Error(t, validator.Validate(invalidUUID))
+
+	_, err = NewSchemaValidator("0")
+	assert.Error(t, err)
+}
+
+func TestInvalidSuggestionsSentToDeadLetter(t *testing.T) {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	deadLetter := &recordingProducer{}
+	deadLetterProducer = deadLetter
+	defer func() { deadLetterProducer = nil }()
+
+	message := producer.Message{Headers: map[string]string{"Message-Id": "a2b3"}, Body: "{}"}
+	sendToDeadLetter("tid_test", "8bd0194e-e501-11e5-9ef8-8db78aefa51e", message, assert.AnError)
+
+	assert.Len(t, deadLetter.messages, 1)
+	assert.Equal(t, "a2b3", deadLetter.messages[0].Headers["Message-Id"])
+	assert.Equal(t, assert.AnError.Error(), deadLetter.messages[0].Headers["Validation-Error"])
+}
+
+func TestGetSchema(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/schemas/concept-suggestion/{version}.json", GetSchema)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/schemas/concept-suggestion/v1.json", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, conceptSuggestionSchemaV1, w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/schemas/concept-suggestion/v9.json", nil))
+	assert.Equal(t, 404, w.Code)
+}
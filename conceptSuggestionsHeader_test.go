@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var publishEventHeaders = map[string]string{
+	"Content-Type":      "application/json",
+	"Message-Id":        "266c7604-b582-47a3-9b7e-c8aad93f1ec9",
+	"Message-Timestamp": "2016-12-29T14:54:10.160Z",
+	"Message-Type":      "cms-content-published",
+	"Origin-System-Id":  bindingService,
+	"X-Request-Id":      "tid_9rvfuynl4b",
+	"X-Trace-Id":        "trace_1",
+}
+
+func TestBuildConceptSuggestionsHeaderLineage(t *testing.T) {
+	headers := buildConceptSuggestionsHeader(publishEventHeaders, HeaderConfig{})
+
+	assert.NotEqual(t, publishEventHeaders["Message-Id"], headers["Message-Id"])
+	assert.Equal(t, "concept-suggestions", headers["Message-Type"])
+	assert.Equal(t, "application/json", headers["Content-Type"])
+	assert.Equal(t, "tid_9rvfuynl4b", headers["X-Request-Id"])
+	assert.Equal(t, bindingService, headers["Origin-System-Id"])
+	assert.Equal(t, "2016-12-29T14:54:10.160Z", headers["Source-Message-Timestamp"])
+	assert.Equal(t, "266c7604-b582-47a3-9b7e-c8aad93f1ec9", headers["Causation-Id"])
+	assert.NotContains(t, headers, "X-Trace-Id")
+}
+
+func TestBuildConceptSuggestionsHeaderWithConfig(t *testing.T) {
+	config := HeaderConfig{
+		OriginSystemID: "http://cmdb.ft.com/systems/v1-suggestor",
+		PassThrough:    []string{"X-Trace-Id", "Message-Type", "X-Missing"},
+	}
+	headers := buildConceptSuggestionsHeader(publishEventHeaders, config)
+
+	assert.Equal(t, "http://cmdb.ft.com/systems/v1-suggestor", headers["Origin-System-Id"])
+	assert.Equal(t, "trace_1", headers["X-Trace-Id"])
+	assert.Equal(t, "concept-suggestions", headers["Message-Type"], "Pass-through headers should not replace the suggestor ones")
+	assert.NotContains(t, headers, "X-Missing")
+}
+
+func TestBuildConceptSuggestionsHeaderNeverPassesThroughBodyHeaders(t *testing.T) {
+	sourceHeaders := map[string]string{"Content-Encoding": "gzip", "content-type": "application/xml", "message-type": "cms-content-published"}
+	for name, value := range publishEventHeaders {
+		sourceHeaders[name] = value
+	}
+	config := HeaderConfig{PassThrough: []string{"Content-Encoding", "content-type", "message-type"}}
+	headers := buildConceptSuggestionsHeader(sourceHeaders, config)
+
+	assert.NotContains(t, headers, "Content-Encoding")
+	assert.NotContains(t, headers, "content-type")
+	assert.NotContains(t, headers, "message-type")
+	assert.Equal(t, "application/json", headers["Content-Type"])
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// ConcordanceEntry maps a V1 term, either by its id or by its taxonomy and external term id, to an existing thing URI
+type ConcordanceEntry struct {
+	V1ID           string `json:"v1Id,omitempty"`
+	Taxonomy       string `json:"taxonomy,omitempty"`
+	ExternalTermID string `json:"externalTermId,omitempty"`
+	Thing          string `json:"thing"`
+}
+
+// ConcordanceTable holds the overrides consulted before minting a thing ID from a V1 term
+type ConcordanceTable struct {
+	file           string
+	byV1ID         map[string]string
+	byExternalTerm map[string]string
+	lock           sync.RWMutex
+}
+
+// NewConcordanceTable returns a table loaded from the given file. An empty file name gives an empty table.
+func NewConcordanceTable(file string) (*ConcordanceTable, error) {
+	table := &ConcordanceTable{
+		file:           file,
+		byV1ID:         map[string]string{},
+		byExternalTerm: map[string]string{},
+	}
+	if err := table.Reload(); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// Reload rereads the concordance file, keeping the current overrides if it cannot be loaded
+func (c *ConcordanceTable) Reload() error {
+	if c.file == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(c.file)
+	if err != nil {
+		return err
+	}
+	var entries []ConcordanceEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	byV1ID := map[string]string{}
+	byExternalTerm := map[string]string{}
+	for i, entry := range entries {
+		if entry.Thing == "" {
+			return fmt.Errorf("Concordance entry %d has no thing", i)
+		}
+		switch {
+		case entry.V1ID != "":
+			byV1ID[entry.V1ID] = entry.Thing
+		case entry.Taxonomy != "" && entry.ExternalTermID != "":
+			byExternalTerm[externalTermKey(entry.Taxonomy, entry.ExternalTermID)] = entry.Thing
+		default:
+			return fmt.Errorf("Concordance entry %d needs either a v1Id or a taxonomy and an externalTermId", i)
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.byV1ID = byV1ID
+	c.byExternalTerm = byExternalTerm
+	return nil
+}
+
+// Resolve returns the overriding thing URI for the term, if any
+func (c *ConcordanceTable) Resolve(t term) (string, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if thing, found := c.byV1ID[t.ID]; found && t.ID != "" {
+		return thing, true
+	}
+	if t.Taxonomy == "" || t.ExternalTermID == "" {
+		return "", false
+	}
+	thing, found := c.byExternalTerm[externalTermKey(t.Taxonomy, t.ExternalTermID)]
+	return thing, found
+}
+
+// Size returns how many overrides are currently loaded
+func (c *ConcordanceTable) Size() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return len(c.byV1ID) + len(c.byExternalTerm)
+}
+
+func externalTermKey(taxonomy string, externalTermID string) string {
+	return strings.ToLower(taxonomy) + "/" + externalTermID
+}
+
+var errUnresolvableTerm = errors.New("Term cannot be resolved without a V1 id")
+
+// ConceptResolution explains how a V1 term is turned into a thing ID
+type ConceptResolution struct {
+	Term   ConcordanceEntry `json:"term"`
+	Thing  string           `json:"thing"`
+	Source string           `json:"source"`
+}
+
+const resolvedByConcordance = "concordance"
+const resolvedByGeneratedID = "generated"
+
+func resolveConcept(concordances *ConcordanceTable, t term) (ConceptResolution, error) {
+	resolution := ConceptResolution{Term: ConcordanceEntry{V1ID: t.ID, Taxonomy: t.Taxonomy, ExternalTermID: t.ExternalTermID}}
+	if thing, found := concordances.Resolve(t); found {
+		resolution.Thing = thing
+		resolution.Source = resolvedByConcordance
+		return resolution, nil
+	}
+	if t.ID == "" {
+		return resolution, errUnresolvableTerm
+	}
+	resolution.Thing = generateID(t.ID)
+	resolution.Source = resolvedByGeneratedID
+	return resolution, nil
+}
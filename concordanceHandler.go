@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+)
+
+// ConcordanceHandler exposes the concordance overrides on the admin endpoints
+type ConcordanceHandler struct {
+	concordances *ConcordanceTable
+}
+
+// NewConcordanceHandler returns a handler for the given concordance table
+func NewConcordanceHandler(concordances *ConcordanceTable) *ConcordanceHandler {
+	return &ConcordanceHandler{concordances: concordances}
+}
+
+// Lookup writes how the V1 term given by the id, or the taxonomy and externalTermId, query parameters resolves
+func (h *ConcordanceHandler) Lookup(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	t := term{
+		ID:             query.Get("id"),
+		Taxonomy:       query.Get("taxonomy"),
+		ExternalTermID: query.Get("externalTermId"),
+	}
+	if t.ID == "" && (t.Taxonomy == "" || t.ExternalTermID == "") {
+		writeJSONMessage(w, http.StatusBadRequest, "Either id or taxonomy and externalTermId query parameters are required")
+		return
+	}
+
+	resolution, err := resolveConcept(h.concordances, t)
+	if err != nil {
+		writeJSONMessage(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, resolution)
+}
+
+// Reload rereads the concordance file
+func (h *ConcordanceHandler) Reload(w http.ResponseWriter, r *http.Request) {
+	if err := h.concordances.Reload(); err != nil {
+		errorLogger.Printf("Error reloading concordances: [%v]", err.Error())
+		writeJSONMessage(w, http.StatusInternalServerError, "Error reloading concordances: "+err.Error())
+		return
+	}
+	infoLogger.Printf("Reloaded [%d] concordances", h.concordances.Size())
+	writeJSON(w, http.StatusOK, map[string]int{"concordances": h.concordances.Size()})
+}
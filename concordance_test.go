@@ -0,0 +1,110 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const overriddenThing = "http://api.ft.com/things/6c3b3e1c-3a3f-4a4e-8ae1-6b0f1ba0ba3f"
+
+func writeConcordanceFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "concordances")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestConcordanceTableResolve(t *testing.T) {
+	file := writeConcordanceFile(t, `[
+		{"v1Id": "Organisation-1-TME", "thing": "`+overriddenThing+`"},
+		{"taxonomy": "ON", "externalTermId": "Nstein_ON_AFTM_ON_9091", "thing": "`+overriddenThing+`"}
+	]`)
+	defer os.Remove(file)
+
+	table, err := NewConcordanceTable(file)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, table.Size())
+
+	tests := []struct {
+		name     string
+		term     term
+		expected string
+		found    bool
+	}{
+		{"Override by V1 id", term{ID: "Organisation-1-TME"}, overriddenThing, true},
+		{"Override by taxonomy and external term id", term{ID: "other", Taxonomy: "on", ExternalTermID: "Nstein_ON_AFTM_ON_9091"}, overriddenThing, true},
+		{"No override", term{ID: "Organisation-2-TME", Taxonomy: "ON", ExternalTermID: "other"}, "", false},
+	}
+
+	for _, test := range tests {
+		thing, found := table.Resolve(test.term)
+		assert.Equal(t, test.found, found, test.name)
+		assert.Equal(t, test.expected, thing, test.name)
+	}
+}
+
+func TestConcordanceTableReloadKeepsOverridesOnError(t *testing.T) {
+	file := writeConcordanceFile(t, `[{"v1Id": "Organisation-1-TME", "thing": "`+overriddenThing+`"}]`)
+	defer os.Remove(file)
+
+	table, err := NewConcordanceTable(file)
+	assert.NoError(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte(`[{"v1Id": "Organisation-1-TME"}]`), 0644))
+	assert.Error(t, table.Reload())
+	assert.Equal(t, 1, table.Size())
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte(`[]`), 0644))
+	assert.NoError(t, table.Reload())
+	assert.Equal(t, 0, table.Size())
+}
+
+func TestBuildSuggestionUsesConcordances(t *testing.T) {
+	file := writeConcordanceFile(t, `[{"v1Id": "`+organisationTMEIDs[0]+`", "thing": "`+overriddenThing+`"}]`)
+	defer os.Remove(file)
+	table, err := NewConcordanceTable(file)
+	assert.NoError(t, err)
+
+	concordances = table
+	defer func() { concordances = nil }()
+
+	suggestions := OrganisationService{"ON"}.buildSuggestions(buildContentRefWithOrganisations(2))
+	assert.Equal(t, overriddenThing, suggestions[0].Thing.ID)
+	assert.Equal(t, generateID(organisationTMEIDs[1]), suggestions[1].Thing.ID)
+}
+
+func TestConcordanceLookupEndpoint(t *testing.T) {
+	file := writeConcordanceFile(t, `[{"v1Id": "Organisation-1-TME", "thing": "`+overriddenThing+`"}]`)
+	defer os.Remove(file)
+	table, err := NewConcordanceTable(file)
+	assert.NoError(t, err)
+	handler := NewConcordanceHandler(table)
+
+	tests := []struct {
+		name         string
+		url          string
+		expectedCode int
+		expectedBody string
+	}{
+		{"Overridden term", "/__admin/concordances?id=Organisation-1-TME", 200, `"thing":"` + overriddenThing + `","source":"concordance"`},
+		{"Generated term", "/__admin/concordances?id=Organisation-2-TME", 200, `"thing":"` + generateID("Organisation-2-TME") + `","source":"generated"`},
+		{"Unknown external term", "/__admin/concordances?taxonomy=ON&externalTermId=other", 404, `"message"`},
+		{"Missing parameters", "/__admin/concordances?taxonomy=ON", 400, `"message"`},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		handler.Lookup(w, httptest.NewRequest("GET", test.url, nil))
+		assert.Equal(t, test.expectedCode, w.Code, test.name)
+		assert.Contains(t, w.Body.String(), test.expectedBody, test.name)
+	}
+}
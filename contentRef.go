@@ -1,10 +1,15 @@
 package main
 
-// ContentRef models the data as it comes from the metadata publishing event
+// ContentRef models the data as it comes from the metadata publishing event. JSON metadata is
+// decoded into contentRefJSON, in metadataDecoding.go, which uses the JSON tags of the structs below.
 type ContentRef struct {
-	TagHolder      tags `xml:"tags"`
-	PrimarySection term `xml:"primarySection"`
-	PrimaryTheme   term `xml:"primaryTheme"`
+	Created            string             `xml:"created,attr"`
+	TagHolder          tags               `xml:"tags"`
+	PrimarySection     term               `xml:"primarySection"`
+	PrimaryTheme       term               `xml:"primaryTheme"`
+	ExternalReferences externalReferences `xml:"externalReferences"`
+	// taxonomyTags indexes the tags by lower case taxonomy, see indexTags
+	taxonomyTags map[string][]tag
 }
 
 type tags struct {
@@ -12,17 +17,27 @@ type tags struct {
 }
 
 type tag struct {
-	Term     term     `xml:"term"`
-	TagScore tagScore `xml:"score"`
+	Term     term     `xml:"term" json:"term"`
+	TagScore tagScore `xml:"score" json:"score"`
 }
 
 type term struct {
-	CanonicalName string `xml:"canonicalName"`
-	Taxonomy      string `xml:"taxonomy,attr"`
-	ID            string `xml:"id,attr"`
+	CanonicalName  string `xml:"canonicalName" json:"canonicalName"`
+	Taxonomy       string `xml:"taxonomy,attr" json:"taxonomy"`
+	ExternalTermID string `xml:"externalTermId,attr" json:"externalTermId"`
+	ID             string `xml:"id,attr" json:"id"`
 }
 
 type tagScore struct {
-	Confidence int `xml:"confidence,attr"`
-	Relevance  int `xml:"relevance,attr"`
+	Confidence int `xml:"confidence,attr" json:"confidence"`
+	Relevance  int `xml:"relevance,attr" json:"relevance"`
+}
+
+type externalReferences struct {
+	References []externalReference `xml:"reference"`
+}
+
+type externalReference struct {
+	ExternalSource string `xml:"externalSource,attr" json:"externalSource"`
+	ExternalID     string `xml:"externalId,attr" json:"externalId"`
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const skippedAsDuplicate = "duplicate"
+
+// DedupCache remembers the Message-Id of the messages seen within the window, for up to maxEntries messages,
+// dropping the oldest first
+type DedupCache struct {
+	window     time.Duration
+	maxEntries int
+	seen       map[string]dedupEntry
+	order      []string
+	next       int
+	now        func() time.Time
+	lock       sync.Mutex
+}
+
+// dedupEntry is when a message id was last remembered, and its slot in the order of the remembered ids.
+// An id remembered again after the window takes a new slot, and its previous slot is stale.
+type dedupEntry struct {
+	seenAt time.Time
+	slot   int
+}
+
+// NewDedupCache returns a cache for the given window, which never reports duplicates when window or maxEntries is 0
+func NewDedupCache(window time.Duration, maxEntries int) *DedupCache {
+	if maxEntries < 0 {
+		maxEntries = 0
+	}
+	return &DedupCache{
+		window:     window,
+		maxEntries: maxEntries,
+		seen:       map[string]dedupEntry{},
+		order:      make([]string, maxEntries),
+		now:        time.Now,
+	}
+}
+
+// isDuplicate returns whether the message id was seen within the window, remembering it otherwise.
+// Messages without id are never duplicates.
+func (d *DedupCache) isDuplicate(messageID string) bool {
+	if messageID == "" || d.window <= 0 || d.maxEntries == 0 {
+		return false
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	now := d.now()
+	if entry, found := d.seen[messageID]; found && now.Sub(entry.seenAt) < d.window {
+		return true
+	}
+	if evicted := d.order[d.next]; evicted != "" && d.seen[evicted].slot == d.next {
+		delete(d.seen, evicted)
+	}
+	d.order[d.next] = messageID
+	d.seen[messageID] = dedupEntry{seenAt: now, slot: d.next}
+	d.next = (d.next + 1) % d.maxEntries
+	return false
+}
+
+// Size returns the number of message ids remembered
+func (d *DedupCache) Size() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return len(d.seen)
+}
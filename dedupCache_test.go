@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/message-queue-gonsumer/consumer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupCacheWindow(t *testing.T) {
+	now := time.Date(2017, 6, 22, 11, 0, 0, 0, time.UTC)
+	d := NewDedupCache(time.Minute, 10)
+	d.now = func() time.Time { return now }
+
+	assert.False(t, d.isDuplicate("a2b3"))
+	assert.True(t, d.isDuplicate("a2b3"))
+	assert.False(t, d.isDuplicate("c4d5"))
+	assert.False(t, d.isDuplicate(""), "Messages without id are never duplicates")
+	assert.False(t, d.isDuplicate(""))
+
+	now = now.Add(time.Minute)
+	assert.False(t, d.isDuplicate("a2b3"), "Should be forgotten after the window")
+	assert.True(t, d.isDuplicate("a2b3"))
+}
+
+func TestDedupCacheMaxEntries(t *testing.T) {
+	d := NewDedupCache(time.Minute, 2)
+	d.isDuplicate("a2b3")
+	d.isDuplicate("c4d5")
+	d.isDuplicate("e6f7")
+
+	assert.Equal(t, 2, d.Size())
+	assert.True(t, d.isDuplicate("e6f7"))
+	assert.False(t, d.isDuplicate("a2b3"), "The oldest id should be forgotten")
+}
+
+func TestDedupCacheRemembersIdsSeenAgainAfterTheWindowAsNewest(t *testing.T) {
+	now := time.Date(2017, 6, 22, 11, 0, 0, 0, time.UTC)
+	d := NewDedupCache(time.Minute, 2)
+	d.now = func() time.Time { return now }
+	d.isDuplicate("a2b3")
+	d.isDuplicate("c4d5")
+
+	now = now.Add(time.Minute)
+	assert.False(t, d.isDuplicate("a2b3"), "Should be forgotten after the window")
+	assert.False(t, d.isDuplicate("e6f7"))
+
+	assert.Equal(t, 2, d.Size())
+	assert.True(t, d.isDuplicate("a2b3"), "The id seen again should not be evicted before older ones")
+	assert.False(t, d.isDuplicate("c4d5"), "The oldest id should be forgotten")
+}
+
+func TestDedupCacheDisabled(t *testing.T) {
+	d := NewDedupCache(0, 10)
+	d.isDuplicate("a2b3")
+	assert.False(t, d.isDuplicate("a2b3"))
+}
+
+func TestConsumeMessageSkipsDuplicates(t *testing.T) {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	defer func(d *DedupCache) { dedupCache = d }(dedupCache)
+	dedupCache = NewDedupCache(time.Minute, 10)
+	messageFilter = MessageFilter{DeniedMessageTypes: []string{"synthetic-publication"}}
+	defer func() { messageFilter = MessageFilter{} }()
+	before := expvarInt(skippedMessages.Get(skippedAsDuplicate))
+
+	msg := consumer.Message{Headers: map[string]string{"Message-Id": "a2b3", "Message-Type": "synthetic-publication"}, Body: `{}`}
+	consumeMessage(msg)
+	consumeMessage(msg)
+
+	assert.Equal(t, before+1, expvarInt(skippedMessages.Get(skippedAsDuplicate)))
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The stages of the message handling at which a source message can fail
+const (
+	stageUnmarshalEvent     = "unmarshalEvent"
+	stageDecodeBody         = "decodeBody"
+	stageUnmarshalMetadata  = "unmarshalMetadata"
+	stageMarshalSuggestions = "marshalSuggestions"
+	stageValidateSchema     = "validateSchema"
+	stageSendMessage        = "sendMessage"
+)
+
+// stageDualWrite counts the failures to send the dual-write schema version, which don't fail the message
+const stageDualWrite = "dualWrite"
+
+const errorRateBuckets = 60
+
+// ErrorRateConfig configures when the error rate is reported as unhealthy
+type ErrorRateConfig struct {
+	Window            time.Duration
+	MinMessages       int
+	Threshold         float64
+	CriticalThreshold float64
+	Severity          uint8
+	FailGTG           bool
+}
+
+// validateSeverity accepts the severities of the FT health checks, 1 being the most severe
+func validateSeverity(severity int) error {
+	if severity < 1 || severity > 3 {
+		return fmt.Errorf("Unknown severity [%d], expected 1, 2 or 3", severity)
+	}
+	return nil
+}
+
+type errorRateBucket struct {
+	start    time.Time
+	total    int
+	failures map[string]int
+}
+
+// ErrorRateTracker keeps the outcome of the processed messages over a rolling window, by failure stage
+type ErrorRateTracker struct {
+	config  ErrorRateConfig
+	buckets []errorRateBucket
+	now     func() time.Time
+	lock    sync.Mutex
+}
+
+// NewErrorRateTracker returns a tracker for the given configuration, with a window of at least a minute
+func NewErrorRateTracker(config ErrorRateConfig) *ErrorRateTracker {
+	if config.Window < time.Minute {
+		config.Window = time.Minute
+	}
+	return &ErrorRateTracker{
+		config:  config,
+		buckets: make([]errorRateBucket, errorRateBuckets),
+		now:     time.Now,
+	}
+}
+
+// Succeeded records a message which was processed successfully
+func (e *ErrorRateTracker) Succeeded() {
+	e.record("")
+}
+
+// Failed records a message which failed at the given stage
+func (e *ErrorRateTracker) Failed(stage string) {
+	failedMessages.Add(stage, 1)
+	e.record(stage)
+}
+
+func (e *ErrorRateTracker) record(failedStage string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	bucket := e.currentBucket()
+	bucket.total++
+	if failedStage != "" {
+		bucket.failures[failedStage]++
+	}
+}
+
+func (e *ErrorRateTracker) currentBucket() *errorRateBucket {
+	bucketSize := e.config.Window / errorRateBuckets
+	start := e.now().Truncate(bucketSize)
+	bucket := &e.buckets[(start.UnixNano()/int64(bucketSize))%errorRateBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = errorRateBucket{start: start, failures: map[string]int{}}
+	}
+	return bucket
+}
+
+// rates returns the number of messages in the window, the overall error rate and the error rate per stage
+func (e *ErrorRateTracker) rates() (int, float64, map[string]float64) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	since := e.now().Add(-e.config.Window)
+	total := 0
+	failures := map[string]int{}
+	for _, bucket := range e.buckets {
+		if bucket.start.IsZero() || !bucket.start.After(since) {
+			continue
+		}
+		total += bucket.total
+		for stage, count := range bucket.failures {
+			failures[stage] += count
+		}
+	}
+
+	stageRates := map[string]float64{}
+	if total == 0 {
+		return 0, 0, stageRates
+	}
+	failed := 0
+	for stage, count := range failures {
+		failed += count
+		stageRates[stage] = float64(count) / float64(total)
+	}
+	return total, float64(failed) / float64(total), stageRates
+}
+
+// Check fails when the error rate is over the threshold
+func (e *ErrorRateTracker) Check() (string, error) {
+	return e.check(e.config.Threshold)
+}
+
+// CriticalCheck fails when the error rate is over the critical threshold
+func (e *ErrorRateTracker) CriticalCheck() (string, error) {
+	return e.check(e.config.CriticalThreshold)
+}
+
+func (e *ErrorRateTracker) check(threshold float64) (string, error) {
+	total, rate, stageRates := e.rates()
+	summary := describeErrorRate(total, rate, stageRates, e.config.Window)
+	if total < e.config.MinMessages || rate <= threshold {
+		return summary, nil
+	}
+	return "", fmt.Errorf("%s is over the threshold of %.1f%%", summary, threshold*100)
+}
+
+func describeErrorRate(total int, rate float64, stageRates map[string]float64, window time.Duration) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Error rate %.1f%% over the last %v (%d messages)", rate*100, window, total)
+
+	var stages []string
+	for stage := range stageRates {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+	for i, stage := range stages {
+		if i == 0 {
+			b.WriteString(":")
+		} else {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, " %s %.1f%%", stage, stageRates[stage]*100)
+	}
+	return b.String()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestErrorRateTracker(now *time.Time) *ErrorRateTracker {
+	e := NewErrorRateTracker(ErrorRateConfig{
+		Window:            time.Minute,
+		MinMessages:       4,
+		Threshold:         0.2,
+		CriticalThreshold: 0.5,
+		Severity:          2,
+	})
+	e.now = func() time.Time { return *now }
+	return e
+}
+
+func TestValidateSeverity(t *testing.T) {
+	for _, severity := range []int{1, 2, 3} {
+		assert.NoError(t, validateSeverity(severity))
+	}
+	assert.EqualError(t, validateSeverity(0), "Unknown severity [0], expected 1, 2 or 3")
+	assert.EqualError(t, validateSeverity(259), "Unknown severity [259], expected 1, 2 or 3")
+}
+
+func TestErrorRateChecks(t *testing.T) {
+	now := time.Date(2017, 6, 22, 11, 0, 0, 0, time.UTC)
+	e := newTestErrorRateTracker(&now)
+
+	e.Failed(stageUnmarshalMetadata)
+	e.Failed(stageSendMessage)
+	_, err := e.Check()
+	assert.NoError(t, err, "Not enough messages to fail")
+
+	e.Succeeded()
+	e.Succeeded()
+	_, err = e.Check()
+	assert.EqualError(t, err, "Error rate 50.0% over the last 1m0s (4 messages): sendMessage 25.0%, unmarshalMetadata 25.0% is over the threshold of 20.0%")
+	_, err = e.CriticalCheck()
+	assert.NoError(t, err, "Error rate is not over the critical threshold")
+
+	e.Failed(stageSendMessage)
+	_, err = e.CriticalCheck()
+	assert.Error(t, err, "Error rate is over the critical threshold")
+}
+
+func TestErrorRateRollingWindow(t *testing.T) {
+	now := time.Date(2017, 6, 22, 11, 0, 0, 0, time.UTC)
+	e := newTestErrorRateTracker(&now)
+
+	for i := 0; i < 4; i++ {
+		e.Failed(stageDecodeBody)
+	}
+	now = now.Add(30 * time.Second)
+	for i := 0; i < 4; i++ {
+		e.Succeeded()
+	}
+	total, rate, _ := e.rates()
+	assert.Equal(t, 8, total)
+	assert.Equal(t, 0.5, rate)
+
+	now = now.Add(31 * time.Second)
+	total, rate, _ = e.rates()
+	assert.Equal(t, 4, total, "Failures should have left the window")
+	assert.Equal(t, 0.0, rate)
+
+	now = now.Add(time.Hour)
+	msg, err := e.Check()
+	assert.NoError(t, err)
+	assert.Equal(t, "Error rate 0.0% over the last 1m0s (0 messages)", msg)
+}
+
+func TestErrorRateHealthCheckAndGTG(t *testing.T) {
+	now := time.Now()
+	e := newTestErrorRateTracker(&now)
+	for i := 0; i < 4; i++ {
+		e.Failed(stageSendMessage)
+	}
+
+	hc := initializeHealthCheck(true, true)
+	hc.addCheck(errorRateCheck(e))
+	w := httptest.NewRecorder()
+	hc.Health()(w, httptest.NewRequest("GET", "http://example.com/__health", nil))
+	assert.Contains(t, w.Body.String(), `"name":"Message Processing Error Rate","ok":false`)
+	assert.True(t, hc.GTG().GoodToGo, "GTG should not fail unless configured")
+
+	hc.addGTGCheck(e.CriticalCheck)
+	status := hc.GTG()
+	assert.False(t, status.GoodToGo)
+	assert.Contains(t, status.Message, "is over the threshold of 50.0%")
+}
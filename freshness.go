@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FreshnessTracker records when source messages were last successfully processed, and since when
+// messages were received without any of them being processed
+type FreshnessTracker struct {
+	window        time.Duration
+	lastProcessed time.Time
+	// unprocessedSince is when the oldest message received since the last processed one was received
+	unprocessedSince time.Time
+	now              func() time.Time
+	lock             sync.RWMutex
+}
+
+// NewFreshnessTracker returns a tracker expecting messages to be processed within the window
+// for as long as messages are being received
+func NewFreshnessTracker(window time.Duration) *FreshnessTracker {
+	return &FreshnessTracker{window: window, now: time.Now}
+}
+
+// Received records that a source message is about to be processed
+func (f *FreshnessTracker) Received() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.unprocessedSince.IsZero() {
+		f.unprocessedSince = f.now()
+	}
+}
+
+// Processed records that the concept suggestions for a source message were sent,
+// observing the latency from the source Message-Timestamp
+func (f *FreshnessTracker) Processed(sourceMessageTimestamp string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.lastProcessed = f.now()
+	f.unprocessedSince = time.Time{}
+
+	published, err := time.Parse(messageTimestampDateFormat, sourceMessageTimestamp)
+	if err != nil {
+		published, err = time.Parse(time.RFC3339Nano, sourceMessageTimestamp)
+	}
+	if err != nil {
+		return
+	}
+	endToEndLatency.Observe(float64(f.lastProcessed.Sub(published) / time.Millisecond))
+}
+
+// Skipped records that a source message was deliberately not sent as concept suggestions, as a stale
+// event or as invalid suggestions, so that it doesn't count as a stall. No latency is observed.
+func (f *FreshnessTracker) Skipped() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.lastProcessed = f.now()
+	f.unprocessedSince = time.Time{}
+}
+
+// LastProcessed returns when the last concept suggestions were sent, or the last source message skipped
+func (f *FreshnessTracker) LastProcessed() time.Time {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.lastProcessed
+}
+
+// Check fails when a message was received more than the window ago and none was processed since.
+// A quiet source is not a stall, however long it lasts.
+func (f *FreshnessTracker) Check() (string, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	if !f.unprocessedSince.IsZero() && f.now().Sub(f.unprocessedSince) > f.window {
+		return "", fmt.Errorf("Messages were received since [%s] but none was processed within %v, last processed at [%s]", formatTimestamp(f.unprocessedSince), f.window, formatTimestamp(f.lastProcessed))
+	}
+	return "Last message processed at " + formatTimestamp(f.lastProcessed), nil
+}
+
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.UTC().Format(messageTimestampDateFormat)
+}
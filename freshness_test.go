@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFreshnessTracker(now *time.Time) *FreshnessTracker {
+	f := NewFreshnessTracker(10 * time.Minute)
+	f.now = func() time.Time { return *now }
+	return f
+}
+
+func TestFreshnessCheck(t *testing.T) {
+	now := time.Date(2017, 6, 22, 11, 0, 0, 0, time.UTC)
+	f := newTestFreshnessTracker(&now)
+
+	_, err := f.Check()
+	assert.NoError(t, err, "Nothing received yet")
+
+	f.Received()
+	now = now.Add(5 * time.Minute)
+	_, err = f.Check()
+	assert.NoError(t, err, "Received within the window")
+
+	f.Received()
+	now = now.Add(6 * time.Minute)
+	_, err = f.Check()
+	assert.Error(t, err, "Received but none processed since the oldest message, outside of the window")
+
+	f.Processed("2017-06-22T11:10:59.000Z")
+	_, err = f.Check()
+	assert.NoError(t, err, "Processed")
+
+	now = now.Add(time.Hour)
+	_, err = f.Check()
+	assert.NoError(t, err, "Source is quiet")
+
+	f.Received()
+	_, err = f.Check()
+	assert.NoError(t, err, "The first message after a quiet period is not a stall")
+
+	now = now.Add(11 * time.Minute)
+	_, err = f.Check()
+	assert.Error(t, err, "Received after the quiet period but not processed within the window")
+}
+
+func TestFreshnessCountsSkippedMessagesAsProcessed(t *testing.T) {
+	now := time.Date(2017, 6, 22, 11, 0, 0, 0, time.UTC)
+	f := newTestFreshnessTracker(&now)
+
+	before := endToEndLatency.count
+	f.Received()
+	now = now.Add(5 * time.Minute)
+	f.Skipped()
+	now = now.Add(11 * time.Minute)
+
+	_, err := f.Check()
+	assert.NoError(t, err, "Stale or invalid messages are not a stall")
+	assert.Equal(t, now.Add(-11*time.Minute), f.LastProcessed())
+	assert.Equal(t, before, endToEndLatency.count)
+}
+
+func TestFreshnessObservesLatency(t *testing.T) {
+	now := time.Date(2017, 6, 22, 11, 0, 0, 0, time.UTC)
+	f := newTestFreshnessTracker(&now)
+
+	before := endToEndLatency.count
+	f.Processed("2017-06-22T10:59:59.800Z")
+	f.Processed("2017-06-22T10:59:58Z")
+	f.Processed("not a timestamp")
+
+	assert.Equal(t, before+2, endToEndLatency.count)
+	assert.Equal(t, now, f.LastProcessed())
+}
+
+func TestHistogram(t *testing.T) {
+	h := &histogram{bounds: []float64{100, 1000}, counts: make([]int64, 2)}
+	h.Observe(50)
+	h.Observe(200)
+	h.Observe(2000)
+
+	assert.JSONEq(t, `{"count": 3, "sum": 2250, "buckets": {"100": 1, "1000": 2, "+Inf": 3}}`, h.String())
+}
+
+func TestHealthCheckWithFreshness(t *testing.T) {
+	now := time.Now()
+	f := newTestFreshnessTracker(&now)
+	f.Received()
+	now = now.Add(11 * time.Minute)
+	hc := initializeHealthCheck(true, true)
+	hc.addCheck(freshnessCheck(f))
+
+	w := httptest.NewRecorder()
+	hc.Health()(w, httptest.NewRequest("GET", "http://example.com/__health", nil))
+
+	assert.Contains(t, w.Body.String(), `"name":"Concept Suggestions Freshness","ok":false`, "Freshness healthcheck should be unhappy")
+}
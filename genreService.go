@@ -14,7 +14,9 @@ func (genreService GenreService) buildSuggestions(contentRef ContentRef) []sugge
 	suggestions := []suggestion{}
 
 	for _, value := range genres {
-		suggestions = append(suggestions, buildSuggestion(value, genreURI, classification))
+		if suggestion, labelled := buildSuggestion(value, genreURI, classification); labelled {
+			suggestions = append(suggestions, suggestion)
+		}
 	}
 
 	return suggestions
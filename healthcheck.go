@@ -10,8 +10,10 @@ import (
 )
 
 type HealthCheck struct {
-	consumer consumer.MessageConsumer
-	producer producer.MessageProducer
+	consumer  consumer.MessageConsumer
+	producer  producer.MessageProducer
+	checks    []fthealth.Check
+	gtgChecks []gtg.StatusChecker
 }
 
 func NewHealthCheck(p producer.MessageProducer, c consumer.MessageConsumer) *HealthCheck {
@@ -22,7 +24,7 @@ func NewHealthCheck(p producer.MessageProducer, c consumer.MessageConsumer) *Hea
 }
 
 func (h *HealthCheck) Health() func(w http.ResponseWriter, r *http.Request) {
-	checks := []fthealth.Check{h.readQueueCheck(), h.writeQueueCheck()}
+	checks := append([]fthealth.Check{h.readQueueCheck(), h.writeQueueCheck()}, h.checks...)
 	hc := fthealth.HealthCheck{
 		SystemCode:  "v1-suggestor",
 		Name:        "V1 Suggestor",
@@ -56,6 +58,66 @@ func (h *HealthCheck) writeQueueCheck() fthealth.Check {
 	}
 }
 
+// addCheck adds a check to the ones reported on the health endpoint
+func (h *HealthCheck) addCheck(check fthealth.Check) {
+	h.checks = append(h.checks, check)
+}
+
+// addGTGCheck adds a checker which can make the service not good to go
+func (h *HealthCheck) addGTGCheck(handler func() (string, error)) {
+	h.gtgChecks = append(h.gtgChecks, func() gtg.Status {
+		return gtgCheck(handler)
+	})
+}
+
+func freshnessCheck(freshness *FreshnessTracker) fthealth.Check {
+	return fthealth.Check{
+		ID:               "suggestions-freshness",
+		Name:             "Concept Suggestions Freshness",
+		Severity:         2,
+		BusinessImpact:   "V1 metadata for newly published content is not reaching the stack. Annotations may be stale.",
+		TechnicalSummary: "V1 metadata publish events are being received, but no concept suggestions were sent within the configured window. Check the logs for processing or queue errors.",
+		PanicGuide:       "https://dewey.ft.com/",
+		Checker:          freshness.Check,
+	}
+}
+
+func errorRateCheck(errorRates *ErrorRateTracker) fthealth.Check {
+	return fthealth.Check{
+		ID:               "message-processing-error-rate",
+		Name:             "Message Processing Error Rate",
+		Severity:         errorRates.config.Severity,
+		BusinessImpact:   "V1 metadata for some content is not reaching the stack. Annotations for that content may be missing or stale.",
+		TechnicalSummary: "Too many V1 metadata publish events failed processing recently. The check output shows the error rate per processing stage; check the logs for the failing messages.",
+		PanicGuide:       "https://dewey.ft.com/",
+		Checker:          errorRates.Check,
+	}
+}
+
+func pausedCheck(consumer *PausableConsumer) fthealth.Check {
+	return fthealth.Check{
+		ID:               "message-consumption-paused",
+		Name:             "Message Consumption Paused",
+		Severity:         2,
+		BusinessImpact:   "V1 metadata publish events are not being read while consumption is paused. Annotations for new content will be delayed.",
+		TechnicalSummary: "Message consumption was paused through POST /__admin/pause. Resume it through POST /__admin/resume.",
+		PanicGuide:       "https://dewey.ft.com/",
+		Checker:          consumer.PausedCheck,
+	}
+}
+
+func circuitBreakerCheck(breaker *CircuitBreaker) fthealth.Check {
+	return fthealth.Check{
+		ID:               "write-queue-circuit-breaker",
+		Name:             "Write Queue Circuit Breaker",
+		Severity:         1,
+		BusinessImpact:   "V1 metadata publish events are not being read while concept suggestions can't be sent. Annotations for new content will be delayed.",
+		TechnicalSummary: "Sending concept suggestions failed repeatedly, so message consumption is paused until the write message queue proxy is reachable again. Check the write queue health check.",
+		PanicGuide:       "https://dewey.ft.com/",
+		Checker:          breaker.Check,
+	}
+}
+
 func (h *HealthCheck) GTG() gtg.Status {
 	consumerCheck := func() gtg.Status {
 		return gtgCheck(h.consumer.ConnectivityCheck)
@@ -64,10 +126,10 @@ func (h *HealthCheck) GTG() gtg.Status {
 		return gtgCheck(h.producer.ConnectivityCheck)
 	}
 
-	return gtg.FailFastParallelCheck([]gtg.StatusChecker{
+	return gtg.FailFastParallelCheck(append([]gtg.StatusChecker{
 		consumerCheck,
 		producerCheck,
-	})()
+	}, h.gtgChecks...))()
 }
 
 func gtgCheck(handler func() (string, error)) gtg.Status {
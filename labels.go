@@ -0,0 +1,43 @@
+package main
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// normaliseLabel cleans up a V1 canonical name before it is used as a prefLabel:
+// HTML entities are decoded, the text is put in Unicode NFC form, control characters are removed
+// and whitespace is trimmed and collapsed to single spaces.
+func normaliseLabel(label string) string {
+	label = html.UnescapeString(label)
+	label = norm.NFC.String(label)
+	label = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, label)
+	return strings.Join(strings.Fields(label), " ")
+}
+
+// findBlankLabelTerms returns the terms of the content which have a canonical name, but a blank one once
+// normalised, so that no suggestion is built for them
+func findBlankLabelTerms(contentRef ContentRef) []term {
+	var blank []term
+	terms := []term{contentRef.PrimarySection, contentRef.PrimaryTheme}
+	for _, tag := range contentRef.TagHolder.Tags {
+		terms = append(terms, tag.Term)
+	}
+	for _, t := range terms {
+		if t.CanonicalName != "" && normaliseLabel(t.CanonicalName) == "" {
+			blank = append(blank, t)
+		}
+	}
+	return blank
+}
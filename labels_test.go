@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormaliseLabel(t *testing.T) {
+	tests := []struct {
+		name     string
+		label    string
+		expected string
+	}{
+		{"Wrapped in newlines and indentation", "\n\tGlobal politics", "Global politics"},
+		{"Repeated inner whitespace", "Middle  East &\t North\nAfrica", "Middle East & North Africa"},
+		{"HTML entities", "UK Politics &amp; Policy &#8211; Q&amp;A", "UK Politics & Policy – Q&A"},
+		{"Decomposed characters", "Luiz Ina\u0301cio Lula da Silva", "Luiz In\u00e1cio Lula da Silva"},
+		{"Control characters", "Brazil\u0000\u0007\u007f", "Brazil"},
+		{"Already normalised", "Dilma Rousseff", "Dilma Rousseff"},
+		{"Blank", " \n ", ""},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, normaliseLabel(test.label), test.name)
+	}
+}
+
+func TestLabelsAreNormalisedForRealMessages(t *testing.T) {
+	setupTaxonomyHandlers()
+
+	validUTF8XML, _ := base64.StdEncoding.DecodeString(validUTF8Metadata)
+	tests := []struct {
+		name     string
+		metadata []byte
+		expected []string
+	}{
+		{"README sample with indented canonical names", []byte(readmeSampleMetadata), []string{"Comment", "Global politics"}},
+		{"Methode article with escaped and accented names", validUTF8XML, []string{"UK Politics & Policy", "Luiz Inácio Lula da Silva", "American Insight"}},
+	}
+
+	for _, test := range tests {
+		metadata, err, _ := unmarshalMetadata(test.metadata)
+		assert.NoError(t, err, test.name)
+
+		labels := map[string]bool{}
+		for _, handler := range taxonomyHandlers {
+			for _, suggestion := range handler.buildSuggestions(metadata) {
+				assert.Equal(t, normaliseLabel(suggestion.Thing.PrefLabel), suggestion.Thing.PrefLabel, test.name)
+				labels[suggestion.Thing.PrefLabel] = true
+			}
+		}
+		for _, label := range test.expected {
+			assert.True(t, labels[label], "%s: expected label [%s] in %v", test.name, label, labels)
+		}
+	}
+}
+
+func TestTagsWithBlankLabelsAreSkipped(t *testing.T) {
+	setupTaxonomyHandlers()
+
+	blank := term{CanonicalName: " &#160;\u0007\n", Taxonomy: "Topics", ID: "VG9waWNz"}
+	metadata := ContentRef{
+		TagHolder:      tags{Tags: []tag{{Term: blank}, {Term: term{CanonicalName: "Global politics", Taxonomy: "Topics", ID: "R2xvYmFs"}}}},
+		PrimarySection: term{CanonicalName: "\t", Taxonomy: "Sections", ID: "U2VjdGlvbnM="},
+		PrimaryTheme:   blank,
+	}
+
+	var labels []string
+	for _, handler := range taxonomyHandlers {
+		for _, suggestion := range handler.buildSuggestions(metadata) {
+			labels = append(labels, suggestion.Thing.PrefLabel)
+		}
+	}
+	assert.Equal(t, []string{"Global politics"}, labels)
+	assert.Equal(t, []term{metadata.PrimarySection, blank, blank}, findBlankLabelTerms(metadata))
+}
+
+const readmeSampleMetadata = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<ns5:contentRef ns5:created="2016-12-29T14:54:10.000Z" ns5:id="3505101"
+	xmlns:ns9="http://metadata.internal.ft.com/metadata/xsd/metadata_taxonomy_v1.0.xsd"
+	xmlns:ns5="http://metadata.internal.ft.com/metadata/xsd/metadata_content_reference_v1.0.xsd"
+	xmlns:ns6="http://metadata.internal.ft.com/metadata/xsd/metadata_tag_v1.0.xsd"
+	xmlns:ns7="http://metadata.internal.ft.com/metadata/xsd/metadata_binding_v1.0.xsd"
+	xmlns:ns1="http://metadata.internal.ft.com/metadata/xsd/metadata_base_v1.0.xsd"
+	xmlns:ns4="http://metadata.internal.ft.com/metadata/xsd/metadata_term_v1.0.xsd">
+	<ns5:primarySection ns4:status="ACTIVE" ns4:externalTermId="116" ns4:taxonomy="Sections" ns1:id="MTE2-U2VjdGlvbnM=">
+	<ns4:canonicalName>
+	Comment</ns4:canonicalName>
+</ns5:primarySection>
+<ns5:primaryTheme ns4:status="ACTIVE" ns4:externalTermId="a8e4a619-3c38-41fd-9e20-8ac64ed06447" ns4:taxonomy="Topics" ns1:id="YThlNGE2MTktM2MzOC00MWZkLTllMjAtOGFjNjRlZDA2NDQ3-VG9waWNz">
+	<ns4:canonicalName>
+	Global politics</ns4:canonicalName>
+</ns5:primaryTheme>
+<ns5:tags>
+	<ns6:tag>
+	<ns6:meta ns1:provenance="USER"/>
+<ns6:term ns4:status="ACTIVE" ns4:externalTermId="a8e4a619-3c38-41fd-9e20-8ac64ed06447" ns4:taxonomy="Topics" ns1:id="YThlNGE2MTktM2MzOC00MWZkLTllMjAtOGFjNjRlZDA2NDQ3-VG9waWNz">
+	<ns4:canonicalName>
+	Global politics</ns4:canonicalName>
+</ns6:term>
+<ns6:score ns6:relevance="100" ns6:confidence="100"/>
+</ns6:tag>
+<ns6:tag>
+	<ns6:meta ns1:provenance="USER"/>
+<ns6:term ns4:status="ACTIVE" ns4:externalTermId="8" ns4:taxonomy="Genres" ns1:id="OA==-R2VucmVz">
+	<ns4:canonicalName>
+	Comment</ns4:canonicalName>
+</ns6:term>
+<ns6:score ns6:relevance="100" ns6:confidence="100"/>
+</ns6:tag>
+<ns6:tag>
+	<ns6:meta ns1:provenance="USER"/>
+<ns6:term ns4:status="ACTIVE" ns4:externalTermId="116" ns4:taxonomy="Sections" ns1:id="MTE2-U2VjdGlvbnM=">
+	<ns4:canonicalName>
+	Comment</ns4:canonicalName>
+</ns6:term>
+<ns6:score ns6:relevance="100" ns6:confidence="100"/>
+</ns6:tag>
+<ns6:tag>
+	<ns6:meta ns1:provenance="PREPROCESSOR"/>
+<ns6:term ns4:status="ACTIVE" ns4:externalTermId="f30ca667-0056-4e98-b41e-f99196e324ef" ns4:taxonomy="MediaTypes" ns1:id="ZjMwY2E2NjctMDA1Ni00ZTk4LWI0MWUtZjk5MTk2ZTMyNGVm-TWVkaWFUeXBlcw==">
+	<ns4:canonicalName>
+	Text</ns4:canonicalName>
+</ns6:term>
+<ns6:score ns6:relevance="100" ns6:confidence="100"/>
+</ns6:tag>
+</ns5:tags>
+<ns5:externalReferences>
+	<ns7:reference ns1:cmrId="1227570" ns1:externalId="980913e6-cdd6-11e6-864f-20dcb35cede2" ns1:externalSource="METHODE"/>
+</ns5:externalReferences>
+</ns5:contentRef>`
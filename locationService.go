@@ -14,13 +14,15 @@ func (locationService LocationService) buildSuggestions(contentRef ContentRef) [
 	suggestions := []suggestion{}
 
 	for _, value := range locations {
-		suggestions = append(suggestions, buildSuggestion(value, locationURI, conceptMajorMentions))
+		if suggestion, labelled := buildSuggestion(value, locationURI, conceptMajorMentions); labelled {
+			suggestions = append(suggestions, suggestion)
+		}
 	}
 
-	if contentRef.PrimaryTheme.CanonicalName != "" {
+	if label := normaliseLabel(contentRef.PrimaryTheme.CanonicalName); label != "" {
 		thing := thing{
-			ID:        generateID(contentRef.PrimaryTheme.ID),
-			PrefLabel: contentRef.PrimaryTheme.CanonicalName,
+			ID:        conceptID(contentRef.PrimaryTheme),
+			PrefLabel: label,
 			Predicate: about,
 			Types:     []string{locationURI},
 		}
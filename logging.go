@@ -5,13 +5,16 @@ import (
 	"log"
 )
 
+var debugLogger *log.Logger
 var infoLogger *log.Logger
 var warnLogger *log.Logger
 var errorLogger *log.Logger
 
 const logPattern = log.Ldate | log.Ltime | log.Lmicroseconds | log.Lshortfile | log.LUTC
 
-func initLogs(infoHandle io.Writer, warnHandle io.Writer, errorHandle io.Writer) {
+func initLogs(debugHandle io.Writer, infoHandle io.Writer, warnHandle io.Writer, errorHandle io.Writer) {
+	//to be used for DEBUG-level logging: debugLogger.Println("foo is now bar")
+	debugLogger = log.New(debugHandle, "DEBUG - ", logPattern)
 	//to be used for INFO-level logging: InfoLogger.Println("foo is now bar")
 	infoLogger = log.New(infoHandle, "INFO  - ", logPattern)
 	//to be used for WARN-level logging: warnLogger.Println("foo is now bar")
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+)
+
+const defaultProfileName = "default"
+
+// MappingProfile is the set of taxonomy handlers applied to the content of one source,
+// selected by the Origin-System-Id header or by the externalSource of the content reference
+type MappingProfile struct {
+	Name            string
+	OriginSystems   []string
+	ExternalSources []string
+	Handlers        map[string]TaxonomyService
+}
+
+// MappingProfiles holds the configured profiles, checked in order, and the default one
+type MappingProfiles struct {
+	Profiles []MappingProfile
+	Default  MappingProfile
+}
+
+// mappingProfileConfig models one profile in the profiles file
+type mappingProfileConfig struct {
+	Name            string                       `json:"name"`
+	OriginSystems   []string                     `json:"originSystems"`
+	ExternalSources []string                     `json:"externalSources"`
+	Taxonomies      []string                     `json:"taxonomies"`
+	Predicates      map[string]map[string]string `json:"predicates"`
+}
+
+// predicateOverride replaces the predicates of the suggestions built by the wrapped handler
+type predicateOverride struct {
+	handler    TaxonomyService
+	predicates map[string]string
+}
+
+func (p predicateOverride) buildSuggestions(contentRef ContentRef) []suggestion {
+	suggestions := p.handler.buildSuggestions(contentRef)
+	for i := range suggestions {
+		if predicate, found := p.predicates[suggestions[i].Thing.Predicate]; found {
+			suggestions[i].Thing.Predicate = predicate
+		}
+	}
+	return suggestions
+}
+
+// newMappingProfiles builds the profiles out of the given taxonomy handlers.
+// Without a profiles file all the content is mapped with every handler.
+func newMappingProfiles(file string, handlers map[string]TaxonomyService) (MappingProfiles, error) {
+	profiles := MappingProfiles{Default: MappingProfile{Name: defaultProfileName, Handlers: handlers}}
+	if file == "" {
+		return profiles, nil
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return profiles, err
+	}
+	var configs []mappingProfileConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return profiles, err
+	}
+
+	for _, config := range configs {
+		profile, err := buildMappingProfile(config, handlers)
+		if err != nil {
+			return profiles, err
+		}
+		if profile.Name == defaultProfileName {
+			profiles.Default = profile
+			continue
+		}
+		profiles.Profiles = append(profiles.Profiles, profile)
+	}
+	return profiles, nil
+}
+
+func buildMappingProfile(config mappingProfileConfig, handlers map[string]TaxonomyService) (MappingProfile, error) {
+	if config.Name == "" {
+		return MappingProfile{}, fmt.Errorf("Mapping profile needs a name")
+	}
+
+	taxonomies := config.Taxonomies
+	if len(taxonomies) == 0 {
+		for key := range handlers {
+			taxonomies = append(taxonomies, key)
+		}
+	}
+
+	profile := MappingProfile{
+		Name:            config.Name,
+		OriginSystems:   config.OriginSystems,
+		ExternalSources: config.ExternalSources,
+		Handlers:        map[string]TaxonomyService{},
+	}
+	for _, key := range taxonomies {
+		handler, found := handlers[key]
+		if !found {
+			return MappingProfile{}, fmt.Errorf("Mapping profile [%s] uses unknown taxonomy [%s]", config.Name, key)
+		}
+		profile.Handlers[key] = handler
+	}
+	for key, predicates := range config.Predicates {
+		handler, found := profile.Handlers[key]
+		if !found {
+			return MappingProfile{}, fmt.Errorf("Mapping profile [%s] overrides predicates of unused taxonomy [%s]", config.Name, key)
+		}
+		profile.Handlers[key] = predicateOverride{handler: handler, predicates: predicates}
+	}
+	return profile, nil
+}
+
+// selectProfile returns the first profile matching the external source of the content,
+// then the first one matching the origin system of the message, then the default profile
+func (p MappingProfiles) selectProfile(headers map[string]string, contentRef ContentRef) MappingProfile {
+	for _, reference := range contentRef.ExternalReferences.References {
+		for _, profile := range p.Profiles {
+			if contains(profile.ExternalSources, reference.ExternalSource) {
+				return profile
+			}
+		}
+	}
+	for _, profile := range p.Profiles {
+		if contains(profile.OriginSystems, headers["Origin-System-Id"]) {
+			return profile
+		}
+	}
+	return p.Default
+}
+
+// taxonomies returns the sorted names of the handlers in the profile
+func (p MappingProfile) taxonomies() []string {
+	var keys []string
+	for key := range p.Handlers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const brightcove = "http://cmdb.ft.com/systems/brightcove"
+
+const testMappingProfiles = `[
+	{"name": "video", "originSystems": ["` + brightcove + `"], "externalSources": ["BRIGHTCOVE"],
+	 "taxonomies": ["brands", "genres"], "predicates": {"brands": {"isClassifiedBy": "isPrimarilyClassifiedBy"}}},
+	{"name": "blog", "externalSources": ["WORDPRESS"], "taxonomies": ["brands"]}
+]`
+
+func loadTestMappingProfiles(t *testing.T, content string) (MappingProfiles, error) {
+	f, err := ioutil.TempFile("", "mapping-profiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	handlers := map[string]TaxonomyService{
+		"brands": BrandService{HandledTaxonomy: "Brands"},
+		"genres": GenreService{HandledTaxonomy: "genres"},
+	}
+	return newMappingProfiles(f.Name(), handlers)
+}
+
+func contentRefFromSource(externalSource string) ContentRef {
+	taxonomyAndCount := map[string]int{"brands": 1, "genres": 1}
+	contentRef := buildContentRef(taxonomyAndCount, false, false)
+	contentRef.ExternalReferences = externalReferences{References: []externalReference{{ExternalSource: externalSource}}}
+	return contentRef
+}
+
+func TestSelectMappingProfile(t *testing.T) {
+	profiles, err := loadTestMappingProfiles(t, testMappingProfiles)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		originSystem    string
+		externalSource  string
+		expectedProfile string
+	}{
+		{"By external source", bindingService, "WORDPRESS", "blog"},
+		{"External source before origin system", brightcove, "WORDPRESS", "blog"},
+		{"By origin system", brightcove, "METHODE", "video"},
+		{"Default", bindingService, "METHODE", defaultProfileName},
+	}
+
+	for _, test := range tests {
+		headers := map[string]string{"Origin-System-Id": test.originSystem}
+		profile := profiles.selectProfile(headers, contentRefFromSource(test.externalSource))
+		assert.Equal(t, test.expectedProfile, profile.Name, test.name)
+	}
+}
+
+func TestMappingProfileHandlers(t *testing.T) {
+	profiles, err := loadTestMappingProfiles(t, testMappingProfiles)
+	assert.NoError(t, err)
+
+	video := profiles.selectProfile(map[string]string{}, contentRefFromSource("BRIGHTCOVE"))
+	assert.Equal(t, []string{"brands", "genres"}, video.taxonomies())
+	brands := video.Handlers["brands"].buildSuggestions(contentRefFromSource("BRIGHTCOVE"))
+	assert.Equal(t, primaryClassification, brands[0].Thing.Predicate)
+	genres := video.Handlers["genres"].buildSuggestions(contentRefFromSource("BRIGHTCOVE"))
+	assert.Equal(t, classification, genres[0].Thing.Predicate)
+
+	blog := profiles.selectProfile(map[string]string{}, contentRefFromSource("WORDPRESS"))
+	assert.Equal(t, []string{"brands"}, blog.taxonomies())
+	assert.Equal(t, classification, blog.Handlers["brands"].buildSuggestions(contentRefFromSource("WORDPRESS"))[0].Thing.Predicate)
+}
+
+func TestInvalidMappingProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"Unknown taxonomy", `[{"name": "video", "taxonomies": ["podcasts"]}]`},
+		{"Predicate override for unused taxonomy", `[{"name": "video", "taxonomies": ["genres"], "predicates": {"brands": {}}}]`},
+		{"Missing name", `[{"taxonomies": ["genres"]}]`},
+		{"Invalid JSON", `{`},
+	}
+
+	for _, test := range tests {
+		_, err := loadTestMappingProfiles(t, test.content)
+		assert.Error(t, err, test.name)
+	}
+}
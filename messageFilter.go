@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+)
+
+const skippedByMessageType = "messageType"
+const skippedByOriginSystem = "originSystem"
+
+// MessageFilter decides from its headers whether a source message is processed.
+// Empty allow lists allow everything; deny lists take precedence over allow lists.
+type MessageFilter struct {
+	AllowedMessageTypes  []string
+	DeniedMessageTypes   []string
+	AllowedOriginSystems []string
+	DeniedOriginSystems  []string
+}
+
+// accept returns whether the message with the given headers should be processed and, if not, why
+func (f MessageFilter) accept(headers map[string]string) (bool, string) {
+	if !allowed(headers["Message-Type"], f.AllowedMessageTypes, f.DeniedMessageTypes) {
+		return false, skippedByMessageType
+	}
+	if !allowed(headers["Origin-System-Id"], f.AllowedOriginSystems, f.DeniedOriginSystems) {
+		return false, skippedByOriginSystem
+	}
+	return true, ""
+}
+
+func allowed(value string, allowList []string, denyList []string) bool {
+	value = strings.TrimSpace(value)
+	if contains(denyList, value) {
+		return false
+	}
+	return len(allowList) == 0 || contains(allowList, value)
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), value) {
+			return true
+		}
+	}
+	return false
+}
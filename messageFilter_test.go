@@ -0,0 +1,72 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/Financial-Times/message-queue-gonsumer/consumer"
+	"github.com/stretchr/testify/assert"
+)
+
+const bindingService = "http://cmdb.ft.com/systems/binding-service"
+
+func TestMessageFilterAccept(t *testing.T) {
+	tests := []struct {
+		name           string
+		filter         MessageFilter
+		headers        map[string]string
+		expectAccepted bool
+		expectReason   string
+	}{
+		{"No filter",
+			MessageFilter{},
+			map[string]string{"Message-Type": "cms-content-published", "Origin-System-Id": bindingService},
+			true, "",
+		},
+		{"Allowed origin system",
+			MessageFilter{AllowedOriginSystems: []string{bindingService}},
+			map[string]string{"Message-Type": "cms-content-published", "Origin-System-Id": bindingService},
+			true, "",
+		},
+		{"Origin system not in allow list",
+			MessageFilter{AllowedOriginSystems: []string{bindingService}},
+			map[string]string{"Message-Type": "cms-content-published", "Origin-System-Id": "http://cmdb.ft.com/systems/synthetic-publication"},
+			false, skippedByOriginSystem,
+		},
+		{"Missing origin system with allow list",
+			MessageFilter{AllowedOriginSystems: []string{bindingService}},
+			map[string]string{"Message-Type": "cms-content-published"},
+			false, skippedByOriginSystem,
+		},
+		{"Denied message type wins over allowed one",
+			MessageFilter{AllowedMessageTypes: []string{"cms-content-published"}, DeniedMessageTypes: []string{"CMS-Content-Published"}},
+			map[string]string{"Message-Type": "cms-content-published", "Origin-System-Id": bindingService},
+			false, skippedByMessageType,
+		},
+	}
+
+	for _, test := range tests {
+		accepted, reason := test.filter.accept(test.headers)
+		assert.Equal(t, test.expectAccepted, accepted, fmt.Sprintf("%s: unexpected acceptance", test.name))
+		assert.Equal(t, test.expectReason, reason, fmt.Sprintf("%s: unexpected reason", test.name))
+	}
+}
+
+func TestConsumeMessageCountsSkippedMessages(t *testing.T) {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	messageFilter = MessageFilter{DeniedMessageTypes: []string{"synthetic-publication"}}
+	defer func() { messageFilter = MessageFilter{} }()
+
+	before := expvarInt(skippedMessages.Get(skippedByMessageType))
+	consumeMessage(consumer.Message{Headers: map[string]string{"Message-Type": "synthetic-publication"}, Body: `{}`})
+	assert.Equal(t, before+1, expvarInt(skippedMessages.Get(skippedByMessageType)))
+}
+
+func expvarInt(v expvar.Var) int64 {
+	if v == nil {
+		return 0
+	}
+	return v.(*expvar.Int).Value()
+}
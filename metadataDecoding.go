@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+	"unicode/utf8"
+)
+
+// The encodings of the value of a metadata publish event
+const (
+	valueEncodingBase64 = "base64"
+	valueEncodingNone   = "none"
+)
+
+// The formats of the metadata in the value of a metadata publish event
+const (
+	metadataFormatXML  = "xml"
+	metadataFormatJSON = "json"
+)
+
+// contentRefJSON is the JSON model of ContentRef, sent by the newer publishers
+type contentRefJSON struct {
+	Created            string              `json:"created"`
+	Tags               []tag               `json:"tags"`
+	PrimarySection     term                `json:"primarySection"`
+	PrimaryTheme       term                `json:"primaryTheme"`
+	ExternalReferences []externalReference `json:"externalReferences"`
+}
+
+func (c contentRefJSON) toContentRef() ContentRef {
+	return ContentRef{
+		Created:            c.Created,
+		TagHolder:          tags{Tags: c.Tags},
+		PrimarySection:     c.PrimarySection,
+		PrimaryTheme:       c.PrimaryTheme,
+		ExternalReferences: externalReferences{References: c.ExternalReferences},
+	}
+}
+
+// decodeValue returns the metadata in the value of the event. A JSON string is decoded according to
+// the value encoding, base64 by default, any other JSON value is the metadata itself.
+func decodeValue(event MetadataPublishEvent) ([]byte, error) {
+	value := bytes.TrimSpace(event.Value)
+	if len(value) == 0 || value[0] != '"' {
+		return value, nil
+	}
+	var str string
+	if err := json.Unmarshal(value, &str); err != nil {
+		return nil, err
+	}
+	switch event.ValueEncoding {
+	case "", valueEncodingBase64:
+		return base64.StdEncoding.DecodeString(str)
+	case valueEncodingNone:
+		return []byte(str), nil
+	default:
+		return nil, fmt.Errorf("Unknown value encoding [%s], expected %s or %s", event.ValueEncoding, valueEncodingBase64, valueEncodingNone)
+	}
+}
+
+// metadataFormat returns the format of the decoded metadata from the Content-Type header. The header
+// historically describes the JSON event itself, so for application/json or no header the format
+// is recognised from the metadata.
+func metadataFormat(contentType string, metadata []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return metadataFormatXML
+	case strings.HasSuffix(mediaType, "+json"):
+		return metadataFormatJSON
+	case bytes.HasPrefix(bytes.TrimSpace(metadata), []byte("{")):
+		return metadataFormatJSON
+	default:
+		return metadataFormatXML
+	}
+}
+
+// unmarshalContentRef unmarshals the decoded metadata in the given format. The returned bool
+// reports whether metadata which failed to unmarshal had invalid UTF-8 characters.
+func unmarshalContentRef(format string, metadata []byte) (ContentRef, error, bool) {
+	if format == metadataFormatJSON {
+		var contentRef contentRefJSON
+		if err := json.Unmarshal(metadata, &contentRef); err != nil {
+			return ContentRef{}, err, !utf8.Valid(metadata)
+		}
+		return contentRef.toContentRef(), nil, false
+	}
+	return unmarshalMetadata(metadata)
+}
+
+func unmarshalMetadata(metadataXML []byte) (ContentRef, error, bool) {
+	metadata, err := decodeContentRef(metadataXML, xmlLimits)
+	if err == nil {
+		return metadata, nil, false
+	}
+	return metadata, err, !utf8.Valid(metadataXML)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readmeSampleContentRefJSON(t *testing.T) []byte {
+	metadata, err, _ := unmarshalMetadata([]byte(readmeSampleMetadata))
+	assert.NoError(t, err)
+	data, err := json.Marshal(contentRefJSON{
+		Created:            metadata.Created,
+		Tags:               metadata.TagHolder.Tags,
+		PrimarySection:     metadata.PrimarySection,
+		PrimaryTheme:       metadata.PrimaryTheme,
+		ExternalReferences: metadata.ExternalReferences.References,
+	})
+	assert.NoError(t, err)
+	return data
+}
+
+func TestDecodeMetadataEncodings(t *testing.T) {
+	expected, _, _ := unmarshalMetadata([]byte(readmeSampleMetadata))
+	contentRefJSON := readmeSampleContentRefJSON(t)
+
+	tests := []struct {
+		name        string
+		contentType string
+		event       MetadataPublishEvent
+	}{
+		{"base64 XML", "application/json", MetadataPublishEvent{
+			Value: json.RawMessage(strconv.Quote(base64.StdEncoding.EncodeToString([]byte(readmeSampleMetadata)))),
+		}},
+		{"plain XML", "application/xml", MetadataPublishEvent{
+			Value: json.RawMessage(strconv.Quote(readmeSampleMetadata)), ValueEncoding: valueEncodingNone,
+		}},
+		{"JSON document", "application/json", MetadataPublishEvent{
+			Value: json.RawMessage(contentRefJSON),
+		}},
+		{"base64 JSON", "application/vnd.ft-content-ref+json", MetadataPublishEvent{
+			Value: json.RawMessage(strconv.Quote(base64.StdEncoding.EncodeToString(contentRefJSON))), ValueEncoding: valueEncodingBase64,
+		}},
+		{"plain JSON", "", MetadataPublishEvent{
+			Value: json.RawMessage(strconv.Quote(string(contentRefJSON))), ValueEncoding: valueEncodingNone,
+		}},
+	}
+
+	for _, test := range tests {
+		value, err := decodeValue(test.event)
+		if !assert.NoError(t, err, test.name) {
+			continue
+		}
+		metadata, err, _ := unmarshalContentRef(metadataFormat(test.contentType, value), value)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, expected, metadata, test.name)
+	}
+}
+
+func TestMetadataFormat(t *testing.T) {
+	assert.Equal(t, metadataFormatXML, metadataFormat("application/json", []byte("<contentRef/>")))
+	assert.Equal(t, metadataFormatXML, metadataFormat("", []byte("<contentRef/>")))
+	assert.Equal(t, metadataFormatXML, metadataFormat("text/xml; charset=utf-8", []byte("{")))
+	assert.Equal(t, metadataFormatJSON, metadataFormat("application/json", []byte(` {"tags": []}`)))
+	assert.Equal(t, metadataFormatJSON, metadataFormat("application/vnd.ft-content-ref+json", []byte("<")))
+}
+
+func TestDecodeValueErrors(t *testing.T) {
+	_, err := decodeValue(MetadataPublishEvent{Value: json.RawMessage(`"not base64!"`)})
+	assert.Error(t, err)
+
+	_, err = decodeValue(MetadataPublishEvent{Value: json.RawMessage(`"PA=="`), ValueEncoding: "gzip"})
+	assert.EqualError(t, err, "Unknown value encoding [gzip], expected base64 or none")
+}
+
+func TestUnmarshalInvalidJSONContentRef(t *testing.T) {
+	_, err, hadInvalidChars := unmarshalContentRef(metadataFormatJSON, []byte(`{"tags": {}}`))
+	assert.Error(t, err)
+	assert.False(t, hadInvalidChars)
+}
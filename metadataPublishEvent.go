@@ -1,7 +1,10 @@
 package main
 
+import "encoding/json"
+
 // MetadataPublishEvent models the events we process from the queue
 type MetadataPublishEvent struct {
-	UUID  string `json:"uuid"`
-	Value string `json:"value"`
+	UUID          string          `json:"uuid"`
+	Value         json.RawMessage `json:"value"`
+	ValueEncoding string          `json:"valueEncoding,omitempty"`
 }
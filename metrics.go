@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"expvar"
+	"fmt"
+	"sync"
+)
+
+// Metrics are published through expvar on /debug/vars
+
+// skippedMessages counts the source messages which were not processed, by reason
+var skippedMessages = expvar.NewMap("skippedMessages")
+
+// failedMessages counts the source messages which failed processing, by stage
+var failedMessages = expvar.NewMap("failedMessages")
+
+// blankLabelTerms counts the tags, primary sections and primary themes skipped because their canonical
+// name is blank once normalised
+var blankLabelTerms = expvar.NewInt("blankLabelTerms")
+
+// endToEndLatency measures the milliseconds between the source Message-Timestamp and sending the concept suggestions
+var endToEndLatency = newHistogram("endToEndLatencyMillis", []float64{50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000})
+
+// histogram is a cumulative expvar histogram with fixed upper bounds
+type histogram struct {
+	bounds []float64
+	counts []int64
+	count  int64
+	sum    float64
+	lock   sync.Mutex
+}
+
+func newHistogram(name string, bounds []float64) *histogram {
+	h := &histogram{bounds: bounds, counts: make([]int64, len(bounds))}
+	expvar.Publish(name, h)
+	return h
+}
+
+// Observe records one value in the histogram
+func (h *histogram) Observe(value float64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.count++
+	h.sum += value
+	for i, bound := range h.bounds {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+}
+
+// String renders the histogram as JSON for expvar
+func (h *histogram) String() string {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `{"count": %d, "sum": %g, "buckets": {`, h.count, h.sum)
+	for i, bound := range h.bounds {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, `"%g": %d`, bound, h.counts[i])
+	}
+	fmt.Fprintf(&b, `, "+Inf": %d}}`, h.count)
+	return b.String()
+}
@@ -14,13 +14,15 @@ func (organisationService OrganisationService) buildSuggestions(contentRef Conte
 	suggestions := []suggestion{}
 
 	for _, value := range subjects {
-		suggestions = append(suggestions, buildSuggestion(value, organisationURI, conceptMajorMentions))
+		if suggestion, labelled := buildSuggestion(value, organisationURI, conceptMajorMentions); labelled {
+			suggestions = append(suggestions, suggestion)
+		}
 	}
 
-	if contentRef.PrimaryTheme.CanonicalName != "" {
+	if label := normaliseLabel(contentRef.PrimaryTheme.CanonicalName); label != "" {
 		thing := thing{
-			ID:        generateID(contentRef.PrimaryTheme.ID),
-			PrefLabel: contentRef.PrimaryTheme.CanonicalName,
+			ID:        conceptID(contentRef.PrimaryTheme),
+			PrefLabel: label,
 			Predicate: about,
 			Types:     []string{organisationURI},
 		}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OutputFormat renders the concept suggestions for a destination
+type OutputFormat interface {
+	ContentType() string
+	Render(ConceptSuggestion) ([]byte, error)
+}
+
+var outputFormats = map[string]OutputFormat{
+	"json":     jsonFormat{},
+	"jsonld":   jsonLDFormat{},
+	"ntriples": nTriplesFormat{},
+	"turtle":   turtleFormat{},
+}
+
+// selectOutputFormat returns the output format with the given name
+func selectOutputFormat(name string) (OutputFormat, error) {
+	format, found := outputFormats[name]
+	if !found {
+		var names []string
+		for n := range outputFormats {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("Unknown output format [%s], expected one of %s", name, strings.Join(names, ", "))
+	}
+	return format, nil
+}
+
+// negotiableFormats are the output formats of the suggestions endpoint, in order of preference
+var negotiableFormats = []OutputFormat{jsonFormat{}, jsonLDFormat{}, nTriplesFormat{}, turtleFormat{}}
+
+// acceptedMediaType is a media range of the Accept header with its quality
+type acceptedMediaType struct {
+	mediaType string
+	quality   float64
+}
+
+// negotiateOutputFormat returns the output format for the media types in the Accept header, by quality
+// then by order of appearance, if it is not plain JSON
+func negotiateOutputFormat(accept string) (OutputFormat, bool) {
+	for _, accepted := range parseAccept(accept) {
+		if accepted.mediaType == "*/*" {
+			return nil, false
+		}
+		for _, format := range negotiableFormats {
+			if !strings.EqualFold(format.ContentType(), accepted.mediaType) {
+				continue
+			}
+			if _, isJSON := format.(jsonFormat); isJSON {
+				return nil, false
+			}
+			return format, true
+		}
+	}
+	return nil, false
+}
+
+// parseAccept returns the media ranges of the Accept header sorted by quality, without the ones
+// not acceptable. Invalid qualities count as 1.
+func parseAccept(accept string) []acceptedMediaType {
+	var accepted []acceptedMediaType
+	for _, mediaRange := range strings.Split(accept, ",") {
+		params := strings.Split(mediaRange, ";")
+		mediaType := acceptedMediaType{mediaType: strings.TrimSpace(params[0]), quality: 1}
+		for _, param := range params[1:] {
+			name, value := param, ""
+			if i := strings.Index(param, "="); i >= 0 {
+				name, value = param[:i], param[i+1:]
+			}
+			if strings.TrimSpace(name) != "q" {
+				continue
+			}
+			if quality, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				mediaType.quality = quality
+			}
+		}
+		if mediaType.mediaType != "" && mediaType.quality > 0 {
+			accepted = append(accepted, mediaType)
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].quality > accepted[j].quality
+	})
+	return accepted
+}
+
+type jsonFormat struct{}
+
+func (jsonFormat) ContentType() string {
+	return "application/json"
+}
+
+func (jsonFormat) Render(conceptSuggestion ConceptSuggestion) ([]byte, error) {
+	return json.Marshal(conceptSuggestion)
+}
+
+const ftContentURIPrefix = "http://api.ft.com/content/"
+
+// predicateURIs maps the predicates to the FT ontology
+var predicateURIs = map[string]string{
+	conceptMentions:       "http://www.ft.com/ontology/annotation/mentions",
+	conceptMajorMentions:  "http://www.ft.com/ontology/annotation/majorMentions",
+	classification:        "http://www.ft.com/ontology/classification/isClassifiedBy",
+	primaryClassification: "http://www.ft.com/ontology/classification/isPrimarilyClassifiedBy",
+	about:                 "http://www.ft.com/ontology/annotation/about",
+	hasAuthor:             "http://www.ft.com/ontology/annotation/hasAuthor",
+}
+
+// predicateURI returns the FT ontology URI of the predicate. Predicates which are not known,
+// e.g. from a mapping profile, are assumed to be annotation predicates unless they already are URIs.
+func predicateURI(predicate string) string {
+	if uri, found := predicateURIs[predicate]; found {
+		return uri
+	}
+	if strings.Contains(predicate, "://") {
+		return predicate
+	}
+	return "http://www.ft.com/ontology/annotation/" + predicate
+}
+
+// jsonLDContext maps the concept suggestion fields to the FT ontology
+var jsonLDContext = map[string]interface{}{
+	"@base":         ftContentURIPrefix,
+	"uuid":          "@id",
+	"id":            "@id",
+	"types":         "@type",
+	"suggestions":   "http://www.ft.com/ontology/annotation/suggestion",
+	"thing":         "http://www.ft.com/ontology/annotation/thing",
+	"prefLabel":     "http://www.w3.org/2004/02/skos/core#prefLabel",
+	"predicate":     map[string]string{"@id": "http://www.ft.com/ontology/annotation/predicate", "@type": "@id"},
+	"provenances":   "http://www.ft.com/ontology/provenance/provenance",
+	"scores":        "http://www.ft.com/ontology/scoring/score",
+	"scoringSystem": map[string]string{"@id": "http://www.ft.com/ontology/scoring/scoringSystem", "@type": "@id"},
+	"value":         map[string]string{"@id": "http://www.ft.com/ontology/scoring/value", "@type": "http://www.w3.org/2001/XMLSchema#float"},
+}
+
+type jsonLDConceptSuggestion struct {
+	Context map[string]interface{} `json:"@context"`
+	ConceptSuggestion
+}
+
+// jsonLDFormat renders the concept suggestions as JSON-LD, in the same shape as the JSON
+// but with the predicates as FT ontology URIs
+type jsonLDFormat struct{}
+
+func (jsonLDFormat) ContentType() string {
+	return "application/ld+json"
+}
+
+func (jsonLDFormat) Render(conceptSuggestion ConceptSuggestion) ([]byte, error) {
+	suggestions := make([]suggestion, len(conceptSuggestion.Suggestions))
+	for i, s := range conceptSuggestion.Suggestions {
+		s.Thing.Predicate = predicateURI(s.Thing.Predicate)
+		suggestions[i] = s
+	}
+	conceptSuggestion.Suggestions = suggestions
+	return json.Marshal(jsonLDConceptSuggestion{Context: jsonLDContext, ConceptSuggestion: conceptSuggestion})
+}
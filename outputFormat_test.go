@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildTestConceptSuggestion() ConceptSuggestion {
+	organisation, _ := buildSuggestion(tag{
+		Term:     term{CanonicalName: "Financial Times", Taxonomy: "ON", ID: "ON-1"},
+		TagScore: tagScore{Confidence: 90, Relevance: 65},
+	}, organisationURI, conceptMentions)
+	return ConceptSuggestion{
+		UUID: "8bd0194e-e501-11e5-9ef8-8db78aefa51e",
+		Suggestions: []suggestion{
+			organisation,
+			{Thing: thing{ID: generateID("SE-1"), PrefLabel: "World", Predicate: primaryClassification, Types: []string{sectionURI}}},
+		},
+	}
+}
+
+func TestJSONLDFormat(t *testing.T) {
+	format, err := selectOutputFormat("jsonld")
+	assert.NoError(t, err)
+	assert.Equal(t, "application/ld+json", format.ContentType())
+
+	conceptSuggestion := buildTestConceptSuggestion()
+	body, err := format.Render(conceptSuggestion)
+	assert.NoError(t, err)
+
+	var document map[string]interface{}
+	assert.NoError(t, json.Unmarshal(body, &document))
+	context := document["@context"].(map[string]interface{})
+	assert.Equal(t, "@type", context["types"])
+	assert.Equal(t, "http://api.ft.com/content/", context["@base"])
+	assert.Equal(t, conceptSuggestion.UUID, document["uuid"])
+
+	suggestions := document["suggestions"].([]interface{})
+	mentions := suggestions[0].(map[string]interface{})["thing"].(map[string]interface{})
+	assert.Equal(t, "http://www.ft.com/ontology/annotation/mentions", mentions["predicate"])
+	primary := suggestions[1].(map[string]interface{})["thing"].(map[string]interface{})
+	assert.Equal(t, "http://www.ft.com/ontology/classification/isPrimarilyClassifiedBy", primary["predicate"])
+
+	assert.Equal(t, conceptMentions, conceptSuggestion.Suggestions[0].Thing.Predicate, "The suggestions should not be changed")
+}
+
+func TestPredicateURI(t *testing.T) {
+	assert.Equal(t, "http://www.ft.com/ontology/annotation/hasAuthor", predicateURI(hasAuthor))
+	assert.Equal(t, "http://www.ft.com/ontology/annotation/implicitlyAbout", predicateURI("implicitlyAbout"))
+	assert.Equal(t, "http://example.com/predicate", predicateURI("http://example.com/predicate"))
+}
+
+func TestSelectUnknownOutputFormat(t *testing.T) {
+	_, err := selectOutputFormat("xml")
+	assert.EqualError(t, err, "Unknown output format [xml], expected one of json, jsonld, ntriples, turtle")
+}
+
+func TestSuggestionsEndpointNegotiatesOutputFormat(t *testing.T) {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	store, cleanup := newTestSuggestionStore(t, 2)
+	defer cleanup()
+	uuid := "8bd0194e-e501-11e5-9ef8-8db78aefa51e"
+	assert.NoError(t, store.Save(buildSuggestionRecord(uuid, "first")))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/content/{uuid}/suggestions", NewSuggestionsHandler(store).GetSuggestions)
+
+	tests := []struct {
+		accept              string
+		expectedContentType string
+	}{
+		{"application/ld+json;q=0.9, application/json", "application/json; charset=UTF-8"},
+		{"application/json;q=0.5, application/ld+json", "application/ld+json"},
+		{"text/turtle;q=0.8, application/n-triples;q=0.8", "text/turtle"},
+		{"application/ld+json;q=0, text/html, */*;q=0.1", "application/json; charset=UTF-8"},
+		{"", "application/json; charset=UTF-8"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/content/"+uuid+"/suggestions", nil)
+		r.Header.Set("Accept", test.accept)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, 200, w.Code, test.accept)
+		assert.Equal(t, test.expectedContentType, w.Header().Get("Content-Type"), test.accept)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Financial-Times/message-queue-go-producer/producer"
+)
+
+// ConceptSuggestionV2 is version 2 of the concept suggestions: the predicates are FT ontology URIs
+// and the provenances carry the time of the source event
+type ConceptSuggestionV2 struct {
+	UUID        string         `json:"uuid"`
+	Annotations []annotationV2 `json:"annotations"`
+}
+
+type annotationV2 struct {
+	Predicate   string         `json:"predicate"`
+	Concept     conceptV2      `json:"concept"`
+	Provenances []provenanceV2 `json:"provenances,omitempty"`
+}
+
+type conceptV2 struct {
+	ID        string   `json:"id"`
+	PrefLabel string   `json:"prefLabel"`
+	Types     []string `json:"types"`
+}
+
+type provenanceV2 struct {
+	AtTime string  `json:"atTime,omitempty"`
+	Scores []score `json:"scores"`
+}
+
+// toConceptSuggestionV2 converts the concept suggestions to version 2, at the time of the source event
+func toConceptSuggestionV2(conceptSuggestion ConceptSuggestion, atTime string) ConceptSuggestionV2 {
+	v2 := ConceptSuggestionV2{UUID: conceptSuggestion.UUID, Annotations: []annotationV2{}}
+	for _, s := range conceptSuggestion.Suggestions {
+		annotation := annotationV2{
+			Predicate: predicateURI(s.Thing.Predicate),
+			Concept:   conceptV2{ID: s.Thing.ID, PrefLabel: s.Thing.PrefLabel, Types: s.Thing.Types},
+		}
+		for _, p := range s.Provenance {
+			annotation.Provenances = append(annotation.Provenances, provenanceV2{AtTime: atTime, Scores: p.Scores})
+		}
+		v2.Annotations = append(v2.Annotations, annotation)
+	}
+	return v2
+}
+
+// OutputVersion renders the concept suggestions in a version of the output schema.
+// Version 1 is rendered in Format, version 2 is JSON only.
+type OutputVersion struct {
+	Version   string
+	Format    OutputFormat
+	Validator *SchemaValidator
+}
+
+// NewOutputVersion returns the output for the schema version, validating it when validate is set
+func NewOutputVersion(version string, format OutputFormat, validate bool) (OutputVersion, error) {
+	output := OutputVersion{Version: version, Format: format}
+	switch version {
+	case "1":
+	case "2":
+		if _, isJSON := format.(jsonFormat); !isJSON {
+			return output, fmt.Errorf("Schema version 2 can only be sent as JSON, not [%s]", format.ContentType())
+		}
+	default:
+		return output, fmt.Errorf("Unknown schema version [%s], expected 1 or 2", version)
+	}
+	if validate {
+		validator, err := NewSchemaValidator(version)
+		if err != nil {
+			return output, err
+		}
+		output.Validator = validator
+	}
+	return output, nil
+}
+
+func (o OutputVersion) model(conceptSuggestion ConceptSuggestion, sourceHeaders map[string]string) interface{} {
+	if o.Version == "2" {
+		return toConceptSuggestionV2(conceptSuggestion, sourceHeaders["Message-Timestamp"])
+	}
+	return conceptSuggestion
+}
+
+// Validate checks the concept suggestions against the schema, when validation is enabled
+func (o OutputVersion) Validate(conceptSuggestion ConceptSuggestion, sourceHeaders map[string]string) error {
+	if o.Validator == nil {
+		return nil
+	}
+	return o.Validator.Validate(o.model(conceptSuggestion, sourceHeaders))
+}
+
+// Message renders the concept suggestions with the given headers, adding their Content-Type and Schema-Version
+func (o OutputVersion) Message(conceptSuggestion ConceptSuggestion, headers map[string]string, sourceHeaders map[string]string) (producer.Message, error) {
+	var body []byte
+	var err error
+	if o.Version == "2" {
+		body, err = json.Marshal(o.model(conceptSuggestion, sourceHeaders))
+	} else {
+		body, err = o.Format.Render(conceptSuggestion)
+	}
+	if err != nil {
+		return producer.Message{}, err
+	}
+
+	messageHeaders := map[string]string{}
+	for name, value := range headers {
+		messageHeaders[name] = value
+	}
+	messageHeaders["Content-Type"] = o.Format.ContentType()
+	messageHeaders["Schema-Version"] = o.Version
+	return producer.Message{Headers: messageHeaders, Body: string(body)}, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConceptSuggestionV2(t *testing.T) {
+	v2 := toConceptSuggestionV2(buildTestConceptSuggestion(), "2016-12-29T14:54:10.160Z")
+
+	assert.Equal(t, "8bd0194e-e501-11e5-9ef8-8db78aefa51e", v2.UUID)
+	assert.Len(t, v2.Annotations, 2)
+	assert.Equal(t, "http://www.ft.com/ontology/annotation/mentions", v2.Annotations[0].Predicate)
+	assert.Equal(t, generateID("ON-1"), v2.Annotations[0].Concept.ID)
+	assert.Equal(t, "2016-12-29T14:54:10.160Z", v2.Annotations[0].Provenances[0].AtTime)
+	assert.Equal(t, "http://www.ft.com/ontology/classification/isPrimarilyClassifiedBy", v2.Annotations[1].Predicate)
+	assert.Empty(t, v2.Annotations[1].Provenances)
+}
+
+func TestOutputVersionMessage(t *testing.T) {
+	output, err := NewOutputVersion("2", jsonFormat{}, true)
+	assert.NoError(t, err)
+	headers := map[string]string{"Message-Type": "concept-suggestions"}
+	sourceHeaders := map[string]string{"Message-Timestamp": "2016-12-29T14:54:10.160Z"}
+
+	message, err := output.Message(buildTestConceptSuggestion(), headers, sourceHeaders)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", message.Headers["Schema-Version"])
+	assert.Equal(t, "application/json", message.Headers["Content-Type"])
+	assert.Equal(t, "concept-suggestions", message.Headers["Message-Type"])
+	assert.NotContains(t, headers, "Schema-Version", "The given headers should not be modified")
+
+	var v2 ConceptSuggestionV2
+	assert.NoError(t, json.Unmarshal([]byte(message.Body), &v2))
+	assert.Equal(t, toConceptSuggestionV2(buildTestConceptSuggestion(), "2016-12-29T14:54:10.160Z"), v2)
+	assert.NoError(t, output.Validate(buildTestConceptSuggestion(), sourceHeaders))
+
+	v1, _ := NewOutputVersion("1", turtleFormat{}, false)
+	message, err = v1.Message(buildTestConceptSuggestion(), headers, sourceHeaders)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", message.Headers["Schema-Version"])
+	assert.Equal(t, "text/turtle", message.Headers["Content-Type"])
+}
+
+func TestInvalidOutputVersions(t *testing.T) {
+	_, err := NewOutputVersion("2", turtleFormat{}, false)
+	assert.EqualError(t, err, "Schema version 2 can only be sent as JSON, not [text/turtle]")
+
+	_, err = NewOutputVersion("3", jsonFormat{}, false)
+	assert.EqualError(t, err, "Unknown schema version [3], expected 1 or 2")
+}
+
+func TestDualWrite(t *testing.T) {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	dualWrite := &recordingProducer{}
+	dualWriteProducer = dualWrite
+	dualWriteOutput, _ = NewOutputVersion("2", jsonFormat{}, false)
+	defer func() { dualWriteProducer = nil }()
+
+	headers := map[string]string{"Message-Id": "a2b3"}
+	sendDualWrite("tid_test", buildTestConceptSuggestion(), headers, map[string]string{})
+
+	assert.Len(t, dualWrite.messages, 1)
+	assert.Equal(t, "a2b3", dualWrite.messages[0].Headers["Message-Id"])
+	assert.Equal(t, "2", dualWrite.messages[0].Headers["Schema-Version"])
+}
+
+func TestDualWriteInItsOwnFormat(t *testing.T) {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	dualWrite := &recordingProducer{}
+	dualWriteProducer = dualWrite
+	dualWriteOutput, _ = NewOutputVersion("1", turtleFormat{}, false)
+	defer func() { dualWriteProducer = nil }()
+
+	sendDualWrite("tid_test", buildTestConceptSuggestion(), map[string]string{}, map[string]string{})
+
+	assert.Len(t, dualWrite.messages, 1)
+	assert.Equal(t, turtleFormat{}.ContentType(), dualWrite.messages[0].Headers["Content-Type"])
+	assert.Equal(t, "1", dualWrite.messages[0].Headers["Schema-Version"])
+}
+
+func TestDualWriteFailureIsCounted(t *testing.T) {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	dualWriteProducer = &recordingProducer{}
+	dualWriteOutput, _ = NewOutputVersion("2", jsonFormat{}, true)
+	defer func() { dualWriteProducer = nil }()
+	before := expvarInt(failedMessages.Get(stageDualWrite))
+
+	invalid := buildTestConceptSuggestion()
+	invalid.UUID = "1234"
+	sendDualWrite("tid_test", invalid, map[string]string{}, map[string]string{})
+
+	assert.Empty(t, dualWriteProducer.(*recordingProducer).messages)
+	assert.Equal(t, before+1, expvarInt(failedMessages.Get(stageDualWrite)))
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/Financial-Times/message-queue-gonsumer/consumer"
+)
+
+var errConsumptionPaused = errors.New("Message consumption is paused")
+
+// PausableConsumer is a consumer.MessageConsumer which can stop polling the queue and resume later.
+// A stopped queue consumer cannot be started again, so a new one is created on every resume.
+type PausableConsumer struct {
+	newConsumer func() consumer.MessageConsumer
+	stateFile   string
+	current     consumer.MessageConsumer
+	done        chan struct{}
+	wake        chan struct{}
+	paused      bool
+	suspended   bool
+	stopped     bool
+	lock        sync.Mutex
+}
+
+type pauseState struct {
+	Paused bool `json:"paused"`
+}
+
+// NewPausableConsumer returns a consumer using newConsumer to create the queue consumers.
+// When stateFile is set the paused state is persisted in it and restored from it.
+func NewPausableConsumer(newConsumer func() consumer.MessageConsumer, stateFile string) *PausableConsumer {
+	p := &PausableConsumer{
+		newConsumer: newConsumer,
+		stateFile:   stateFile,
+		current:     newConsumer(),
+		wake:        make(chan struct{}),
+	}
+	p.paused = p.loadState()
+	return p
+}
+
+// Start consumes messages until Stop is called, waiting while consumption is paused or suspended
+func (p *PausableConsumer) Start() {
+	for {
+		p.lock.Lock()
+		if p.stopped {
+			p.lock.Unlock()
+			return
+		}
+		if !p.consuming() {
+			wake := p.wake
+			p.lock.Unlock()
+			<-wake
+			continue
+		}
+		c := p.current
+		done := make(chan struct{})
+		p.done = done
+		p.lock.Unlock()
+
+		c.Start()
+
+		p.lock.Lock()
+		p.done = nil
+		if !p.stopped {
+			p.current = p.newConsumer()
+		}
+		p.lock.Unlock()
+		close(done)
+	}
+}
+
+// Stop stops consuming messages and makes Start return
+func (p *PausableConsumer) Stop() {
+	p.update(func() { p.stopped = true })
+}
+
+// Pause stops polling the queue until Resume is called
+func (p *PausableConsumer) Pause() error {
+	p.update(func() { p.paused = true })
+	return p.saveState()
+}
+
+// Resume starts polling the queue again, unless consumption is suspended
+func (p *PausableConsumer) Resume() error {
+	p.update(func() { p.paused = false })
+	return p.saveState()
+}
+
+// Suspend stops polling the queue until Unsuspend is called, independently of Pause and Resume.
+// The suspended state is not persisted.
+func (p *PausableConsumer) Suspend() {
+	p.update(func() { p.suspended = true })
+}
+
+// Unsuspend starts polling the queue again, unless consumption is paused
+func (p *PausableConsumer) Unsuspend() {
+	p.update(func() { p.suspended = false })
+}
+
+// update applies the change, stopping the running queue consumer or waking Start up as needed
+func (p *PausableConsumer) update(change func()) {
+	p.lock.Lock()
+	change()
+	c, done := p.current, p.done
+	stop := done != nil && (p.stopped || !p.consuming())
+	if stop {
+		p.done = nil
+	}
+	p.wakeUp()
+	p.lock.Unlock()
+
+	if stop {
+		c.Stop()
+		<-done
+	}
+}
+
+func (p *PausableConsumer) consuming() bool {
+	return !p.paused && !p.suspended
+}
+
+// Paused returns whether consumption is paused
+func (p *PausableConsumer) Paused() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.paused
+}
+
+// ConnectivityCheck checks the connectivity of the current queue consumer, even when paused
+func (p *PausableConsumer) ConnectivityCheck() (string, error) {
+	p.lock.Lock()
+	c := p.current
+	p.lock.Unlock()
+	return c.ConnectivityCheck()
+}
+
+// PausedCheck fails while consumption is paused
+func (p *PausableConsumer) PausedCheck() (string, error) {
+	if p.Paused() {
+		return "", errConsumptionPaused
+	}
+	return "Message consumption is running", nil
+}
+
+func (p *PausableConsumer) wakeUp() {
+	close(p.wake)
+	p.wake = make(chan struct{})
+}
+
+func (p *PausableConsumer) loadState() bool {
+	if p.stateFile == "" {
+		return false
+	}
+	data, err := ioutil.ReadFile(p.stateFile)
+	if os.IsNotExist(err) {
+		return false
+	}
+	if err != nil {
+		errorLogger.Printf("Error reading pause state from [%s]: [%v]", p.stateFile, err.Error())
+		return false
+	}
+	var state pauseState
+	if err := json.Unmarshal(data, &state); err != nil {
+		errorLogger.Printf("Error reading pause state from [%s]: [%v]", p.stateFile, err.Error())
+		return false
+	}
+	return state.Paused
+}
+
+func (p *PausableConsumer) saveState() error {
+	if p.stateFile == "" {
+		return nil
+	}
+	data, _ := json.Marshal(pauseState{Paused: p.Paused()})
+	return ioutil.WriteFile(p.stateFile, data, 0644)
+}
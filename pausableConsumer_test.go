@@ -0,0 +1,103 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/Financial-Times/message-queue-gonsumer/consumer"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingConsumer consumes until stopped, like the queue consumer
+type blockingConsumer struct {
+	started chan struct{}
+	stop    chan struct{}
+}
+
+func (c *blockingConsumer) Start() {
+	close(c.started)
+	<-c.stop
+}
+
+func (c *blockingConsumer) Stop() {
+	close(c.stop)
+}
+
+func (c *blockingConsumer) ConnectivityCheck() (string, error) {
+	return "", nil
+}
+
+type consumerFactory struct {
+	created []*blockingConsumer
+	lock    sync.Mutex
+}
+
+func (f *consumerFactory) newConsumer() consumer.MessageConsumer {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	c := &blockingConsumer{started: make(chan struct{}), stop: make(chan struct{})}
+	f.created = append(f.created, c)
+	return c
+}
+
+func (f *consumerFactory) consumer(i int) *blockingConsumer {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.created[i]
+}
+
+func TestPauseAndResume(t *testing.T) {
+	f := &consumerFactory{}
+	p := NewPausableConsumer(f.newConsumer, "")
+	finished := make(chan struct{})
+	go func() {
+		p.Start()
+		close(finished)
+	}()
+	<-f.consumer(0).started
+
+	assert.NoError(t, p.Pause())
+	assert.True(t, p.Paused())
+	_, err := p.PausedCheck()
+	assert.EqualError(t, err, "Message consumption is paused")
+	_, err = p.ConnectivityCheck()
+	assert.NoError(t, err, "Connectivity is checked while paused")
+
+	assert.NoError(t, p.Resume())
+	<-f.consumer(1).started
+	_, err = p.PausedCheck()
+	assert.NoError(t, err)
+
+	p.Stop()
+	<-finished
+}
+
+func TestPausedStateIsPersisted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pause")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	stateFile := filepath.Join(dir, "state.json")
+
+	f := &consumerFactory{}
+	p := NewPausableConsumer(f.newConsumer, stateFile)
+	assert.False(t, p.Paused())
+	assert.NoError(t, p.Pause())
+
+	restarted := NewPausableConsumer(f.newConsumer, stateFile)
+	assert.True(t, restarted.Paused(), "Should start paused")
+	finished := make(chan struct{})
+	go func() {
+		restarted.Start()
+		close(finished)
+	}()
+	restarted.Stop()
+	<-finished
+	select {
+	case <-f.consumer(1).started:
+		t.Error("Should not have consumed while paused")
+	default:
+	}
+}
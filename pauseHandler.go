@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+)
+
+// PauseHandler pauses and resumes the message consumption on the admin endpoints
+type PauseHandler struct {
+	consumer *PausableConsumer
+}
+
+// NewPauseHandler returns a handler for the given consumer
+func NewPauseHandler(consumer *PausableConsumer) *PauseHandler {
+	return &PauseHandler{consumer: consumer}
+}
+
+// Pause stops polling the queue, waiting for the message being processed
+func (h *PauseHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	if err := h.consumer.Pause(); err != nil {
+		errorLogger.Printf("Error saving the paused state: [%v]", err.Error())
+	}
+	infoLogger.Printf("Message consumption paused")
+	writeJSON(w, http.StatusOK, pauseState{Paused: h.consumer.Paused()})
+}
+
+// Resume starts polling the queue again
+func (h *PauseHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	if err := h.consumer.Resume(); err != nil {
+		errorLogger.Printf("Error saving the paused state: [%v]", err.Error())
+	}
+	infoLogger.Printf("Message consumption resumed")
+	writeJSON(w, http.StatusOK, pauseState{Paused: h.consumer.Paused()})
+}
@@ -14,13 +14,15 @@ func (peopleService PeopleService) buildSuggestions(contentRef ContentRef) []sug
 	suggestions := []suggestion{}
 
 	for _, value := range people {
-		suggestions = append(suggestions, buildSuggestion(value, personURI, conceptMajorMentions))
+		if suggestion, labelled := buildSuggestion(value, personURI, conceptMajorMentions); labelled {
+			suggestions = append(suggestions, suggestion)
+		}
 	}
 
-	if contentRef.PrimaryTheme.CanonicalName != "" {
+	if label := normaliseLabel(contentRef.PrimaryTheme.CanonicalName); label != "" {
 		thing := thing{
-			ID:        generateID(contentRef.PrimaryTheme.ID),
-			PrefLabel: contentRef.PrimaryTheme.CanonicalName,
+			ID:        conceptID(contentRef.PrimaryTheme),
+			PrefLabel: label,
 			Predicate: about,
 			Types:     []string{personURI},
 		}
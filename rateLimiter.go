@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Financial-Times/message-queue-go-producer/producer"
+)
+
+const maxRateLimitSleep = time.Second
+
+// RateLimit is the rate at which messages are sent, unlimited when MessagesPerSecond is 0
+type RateLimit struct {
+	MessagesPerSecond float64 `json:"messagesPerSecond"`
+	Burst             int     `json:"burst"`
+}
+
+// RateLimitedProducer is a producer.MessageProducer sending messages at most at the rate limit,
+// with a token bucket. SendMessage blocks until a token is available, slowing down consumption.
+type RateLimitedProducer struct {
+	producer producer.MessageProducer
+	limit    RateLimit
+	tokens   float64
+	last     time.Time
+	now      func() time.Time
+	sleep    func(time.Duration)
+	lock     sync.Mutex
+}
+
+// NewRateLimitedProducer returns a producer sending messages through p at most at the rate limit
+func NewRateLimitedProducer(p producer.MessageProducer, limit RateLimit) (*RateLimitedProducer, error) {
+	r := &RateLimitedProducer{producer: p, now: time.Now, sleep: time.Sleep}
+	if err := r.SetLimit(limit); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SendMessage waits for a token and sends the message
+func (r *RateLimitedProducer) SendMessage(uuid string, message producer.Message) error {
+	r.wait()
+	return r.producer.SendMessage(uuid, message)
+}
+
+// ConnectivityCheck checks the connectivity of the producer
+func (r *RateLimitedProducer) ConnectivityCheck() (string, error) {
+	return r.producer.ConnectivityCheck()
+}
+
+// Limit returns the current rate limit
+func (r *RateLimitedProducer) Limit() RateLimit {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.limit
+}
+
+// SetLimit changes the rate limit, starting with a full bucket
+func (r *RateLimitedProducer) SetLimit(limit RateLimit) error {
+	if limit.MessagesPerSecond < 0 {
+		return fmt.Errorf("Invalid rate limit of %v messages per second, expected 0 for unlimited or more", limit.MessagesPerSecond)
+	}
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.limit = limit
+	r.tokens = float64(limit.Burst)
+	r.last = r.now()
+	return nil
+}
+
+func (r *RateLimitedProducer) wait() {
+	for {
+		delay := r.take()
+		if delay == 0 {
+			return
+		}
+		// sleep in steps so that a new rate limit applies quickly
+		if delay > maxRateLimitSleep {
+			delay = maxRateLimitSleep
+		}
+		r.sleep(delay)
+	}
+}
+
+// take takes a token, or returns how long until the next token is available
+func (r *RateLimitedProducer) take() time.Duration {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.limit.MessagesPerSecond == 0 {
+		return 0
+	}
+	now := r.now()
+	r.tokens += now.Sub(r.last).Seconds() * r.limit.MessagesPerSecond
+	if r.tokens > float64(r.limit.Burst) {
+		r.tokens = float64(r.limit.Burst)
+	}
+	r.last = now
+	if r.tokens >= 1 {
+		r.tokens--
+		return 0
+	}
+	return time.Duration((1 - r.tokens) / r.limit.MessagesPerSecond * float64(time.Second))
+}
+
+// RateLimitHandler reads and changes the rate limit on the admin endpoints
+type RateLimitHandler struct {
+	producer *RateLimitedProducer
+}
+
+// NewRateLimitHandler returns a handler for the given producer
+func NewRateLimitHandler(producer *RateLimitedProducer) *RateLimitHandler {
+	return &RateLimitHandler{producer: producer}
+}
+
+// GetLimit writes the current rate limit
+func (h *RateLimitHandler) GetLimit(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.producer.Limit())
+}
+
+// SetLimit changes the rate limit to the one in the request body
+func (h *RateLimitHandler) SetLimit(w http.ResponseWriter, r *http.Request) {
+	limit := h.producer.Limit()
+	if err := json.NewDecoder(r.Body).Decode(&limit); err != nil {
+		writeJSONMessage(w, http.StatusBadRequest, "Invalid rate limit: "+err.Error())
+		return
+	}
+	if err := h.producer.SetLimit(limit); err != nil {
+		writeJSONMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	infoLogger.Printf("Rate limit changed to %v messages per second with bursts of %d", limit.MessagesPerSecond, h.producer.Limit().Burst)
+	writeJSON(w, http.StatusOK, h.producer.Limit())
+}
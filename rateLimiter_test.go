@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/message-queue-go-producer/producer"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRateLimitedProducer(t *testing.T, limit RateLimit) (*RateLimitedProducer, *time.Duration) {
+	now := time.Date(2017, 6, 22, 11, 0, 0, 0, time.UTC)
+	slept := time.Duration(0)
+	r, err := NewRateLimitedProducer(&mockProducerInstance{isConnectionHealthy: true}, RateLimit{})
+	assert.NoError(t, err)
+	r.now = func() time.Time { return now }
+	r.sleep = func(d time.Duration) {
+		slept += d
+		now = now.Add(d)
+	}
+	assert.NoError(t, r.SetLimit(limit))
+	return r, &slept
+}
+
+func TestRateLimit(t *testing.T) {
+	r, slept := newTestRateLimitedProducer(t, RateLimit{MessagesPerSecond: 2, Burst: 3})
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, r.SendMessage("uuid", producer.Message{}))
+	}
+	assert.Equal(t, time.Duration(0), *slept, "The burst should be sent at once")
+
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, r.SendMessage("uuid", producer.Message{}))
+	}
+	assert.Equal(t, 2*time.Second, *slept)
+}
+
+func TestRateLimitUnlimited(t *testing.T) {
+	r, slept := newTestRateLimitedProducer(t, RateLimit{})
+	for i := 0; i < 100; i++ {
+		r.SendMessage("uuid", producer.Message{})
+	}
+	assert.Equal(t, time.Duration(0), *slept)
+
+	assert.Error(t, r.SetLimit(RateLimit{MessagesPerSecond: -1}))
+}
+
+func TestRateLimitHandler(t *testing.T) {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	r, _ := newTestRateLimitedProducer(t, RateLimit{MessagesPerSecond: 2, Burst: 3})
+	h := NewRateLimitHandler(r)
+
+	w := httptest.NewRecorder()
+	h.SetLimit(w, httptest.NewRequest("PUT", "/__admin/rate-limit", strings.NewReader(`{"messagesPerSecond": 0.5}`)))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, RateLimit{MessagesPerSecond: 0.5, Burst: 3}, r.Limit())
+
+	w = httptest.NewRecorder()
+	h.SetLimit(w, httptest.NewRequest("PUT", "/__admin/rate-limit", strings.NewReader(`{"messagesPerSecond": -2}`)))
+	assert.Equal(t, 400, w.Code)
+
+	w = httptest.NewRecorder()
+	h.GetLimit(w, httptest.NewRequest("GET", "/__admin/rate-limit", nil))
+	assert.JSONEq(t, `{"messagesPerSecond": 0.5, "burst": 3}`, w.Body.String())
+}
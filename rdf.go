@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	rdfNamespace     = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	skosNamespace    = "http://www.w3.org/2004/02/skos/core#"
+	xsdNamespace     = "http://www.w3.org/2001/XMLSchema#"
+	scoringNamespace = "http://www.ft.com/ontology/scoring/"
+)
+
+// The predicates of the RDF statements, besides the annotation predicates
+const (
+	rdfType          = rdfNamespace + "type"
+	rdfStatement     = rdfNamespace + "Statement"
+	rdfSubject       = rdfNamespace + "subject"
+	rdfPredicate     = rdfNamespace + "predicate"
+	rdfObject        = rdfNamespace + "object"
+	skosPrefLabel    = skosNamespace + "prefLabel"
+	xsdFloat         = xsdNamespace + "float"
+	ftProvenance     = "http://www.ft.com/ontology/provenance/provenance"
+	ftScore          = scoringNamespace + "score"
+	ftScoringSystem  = scoringNamespace + "scoringSystem"
+	ftScoreValue     = scoringNamespace + "value"
+	ftThingURIPrefix = "http://api.ft.com/things/"
+)
+
+// turtlePrefixes are the prefixes used to abbreviate the IRIs in Turtle, in declaration order
+var turtlePrefixes = []struct{ name, namespace string }{
+	{"rdf", rdfNamespace},
+	{"skos", skosNamespace},
+	{"xsd", xsdNamespace},
+	{"ft", "http://www.ft.com/ontology/"},
+	{"annotation", "http://www.ft.com/ontology/annotation/"},
+	{"classification", "http://www.ft.com/ontology/classification/"},
+	{"provenance", "http://www.ft.com/ontology/provenance/"},
+	{"scoring", scoringNamespace},
+	{"content", ftContentURIPrefix},
+	{"things", ftThingURIPrefix},
+}
+
+var turtleLocalName = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_-]*$`)
+
+// rdfTerm is an IRI, a blank node or a literal with an optional datatype
+type rdfTerm struct {
+	iri      string
+	blank    string
+	literal  string
+	datatype string
+}
+
+type triple struct {
+	subject, predicate, object rdfTerm
+}
+
+func iri(value string) rdfTerm {
+	return rdfTerm{iri: value}
+}
+
+func blank(label string) rdfTerm {
+	return rdfTerm{blank: label}
+}
+
+// conceptSuggestionTriples expresses the concept suggestions as RDF statements. Each annotation is
+// reified as the blank node _:s{i}, in the order of the suggestions, carrying its score provenances.
+func conceptSuggestionTriples(conceptSuggestion ConceptSuggestion) []triple {
+	content := iri(ftContentURIPrefix + conceptSuggestion.UUID)
+	var triples []triple
+	for i, s := range conceptSuggestion.Suggestions {
+		concept := iri(s.Thing.ID)
+		predicate := iri(predicateURI(s.Thing.Predicate))
+		statement := blank(fmt.Sprintf("s%d", i))
+
+		triples = append(triples, triple{content, predicate, concept})
+		for _, t := range s.Thing.Types {
+			triples = append(triples, triple{concept, iri(rdfType), iri(t)})
+		}
+		triples = append(triples,
+			triple{concept, iri(skosPrefLabel), rdfTerm{literal: s.Thing.PrefLabel}},
+			triple{statement, iri(rdfType), iri(rdfStatement)},
+			triple{statement, iri(rdfSubject), content},
+			triple{statement, iri(rdfPredicate), predicate},
+			triple{statement, iri(rdfObject), concept},
+		)
+		for j, p := range s.Provenance {
+			provenance := blank(fmt.Sprintf("s%dp%d", i, j))
+			triples = append(triples, triple{statement, iri(ftProvenance), provenance})
+			for k, sc := range p.Scores {
+				score := blank(fmt.Sprintf("s%dp%ds%d", i, j, k))
+				value := strconv.FormatFloat(float64(sc.Value), 'g', -1, 32)
+				triples = append(triples,
+					triple{provenance, iri(ftScore), score},
+					triple{score, iri(ftScoringSystem), iri(sc.ScoringSystem)},
+					triple{score, iri(ftScoreValue), rdfTerm{literal: value, datatype: xsdFloat}},
+				)
+			}
+		}
+	}
+	return triples
+}
+
+// writeTerm writes the term in N-Triples syntax, or abbreviated in Turtle syntax when turtle is set
+func writeTerm(b *bytes.Buffer, t rdfTerm, turtle bool) {
+	switch {
+	case t.iri != "":
+		writeIRI(b, t.iri, turtle)
+	case t.blank != "":
+		b.WriteString("_:" + t.blank)
+	default:
+		b.WriteString(`"` + escapeRDFLiteral(t.literal) + `"`)
+		if t.datatype != "" {
+			b.WriteString("^^")
+			writeIRI(b, t.datatype, turtle)
+		}
+	}
+}
+
+func writeIRI(b *bytes.Buffer, value string, turtle bool) {
+	value = escapeIRI(value)
+	if turtle {
+		for _, prefix := range turtlePrefixes {
+			local := strings.TrimPrefix(value, prefix.namespace)
+			if strings.HasPrefix(value, prefix.namespace) && turtleLocalName.MatchString(local) {
+				b.WriteString(prefix.name + ":" + local)
+				return
+			}
+		}
+	}
+	b.WriteString("<" + value + ">")
+}
+
+// escapeIRI percent-encodes the characters not allowed in an IRI reference, such as spaces or angle brackets
+// in the concept IDs of the concordances, so that they can't end the IRI or break the statement
+func escapeIRI(value string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c <= 0x20 || strings.IndexByte(`<>"{}|^`+"`"+`\`, c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+var rdfLiteralEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+
+func escapeRDFLiteral(value string) string {
+	return rdfLiteralEscaper.Replace(value)
+}
+
+func writeTriples(b *bytes.Buffer, triples []triple, turtle bool) {
+	for _, t := range triples {
+		writeTerm(b, t.subject, turtle)
+		b.WriteString(" ")
+		writeTerm(b, t.predicate, turtle)
+		b.WriteString(" ")
+		writeTerm(b, t.object, turtle)
+		b.WriteString(" .\n")
+	}
+}
+
+type nTriplesFormat struct{}
+
+func (nTriplesFormat) ContentType() string {
+	return "application/n-triples"
+}
+
+func (nTriplesFormat) Render(conceptSuggestion ConceptSuggestion) ([]byte, error) {
+	var b bytes.Buffer
+	writeTriples(&b, conceptSuggestionTriples(conceptSuggestion), false)
+	return b.Bytes(), nil
+}
+
+// turtleFormat renders the same statements as nTriplesFormat, one per line, with the IRIs abbreviated
+type turtleFormat struct{}
+
+func (turtleFormat) ContentType() string {
+	return "text/turtle"
+}
+
+func (turtleFormat) Render(conceptSuggestion ConceptSuggestion) ([]byte, error) {
+	var b bytes.Buffer
+	for _, prefix := range turtlePrefixes {
+		fmt.Fprintf(&b, "@prefix %s: <%s> .\n", prefix.name, prefix.namespace)
+	}
+	b.WriteString("\n")
+	writeTriples(&b, conceptSuggestionTriples(conceptSuggestion), true)
+	return b.Bytes(), nil
+}
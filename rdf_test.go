@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rdfTermPattern matches an IRI, a prefixed name, a blank node or a literal with an optional datatype
+var rdfTermPattern = regexp.MustCompile(`^(<[^>]*>|_:[A-Za-z0-9]+|"(?:[^"\\]|\\.)*"(?:\^\^(?:<[^>]*>|[A-Za-z]+:[A-Za-z0-9_-]+))?|[A-Za-z]+:[A-Za-z0-9_-]+)\s*`)
+
+var rdfPrefixPattern = regexp.MustCompile(`^@prefix ([A-Za-z]+): <([^>]*)> \.$`)
+
+var rdfLiteralUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n", `\r`, "\r")
+
+// parseTriples parses the one statement per line subset of N-Triples and Turtle written by the RDF formats
+func parseTriples(t *testing.T, data []byte) []triple {
+	prefixes := map[string]string{}
+	parseIRI := func(value string) string {
+		if strings.HasPrefix(value, "<") {
+			return strings.Trim(value, "<>")
+		}
+		parts := strings.SplitN(value, ":", 2)
+		namespace, found := prefixes[parts[0]]
+		assert.True(t, found, "Undeclared prefix in [%s]", value)
+		return namespace + parts[1]
+	}
+	parseTerm := func(value string) rdfTerm {
+		switch {
+		case strings.HasPrefix(value, "_:"):
+			return blank(strings.TrimPrefix(value, "_:"))
+		case strings.HasPrefix(value, `"`):
+			end := strings.LastIndex(value, `"`)
+			term := rdfTerm{literal: rdfLiteralUnescaper.Replace(value[1:end])}
+			if datatype := strings.TrimPrefix(value[end+1:], "^^"); datatype != "" {
+				term.datatype = parseIRI(datatype)
+			}
+			return term
+		default:
+			return iri(parseIRI(value))
+		}
+	}
+
+	var triples []triple
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if match := rdfPrefixPattern.FindStringSubmatch(line); match != nil {
+			prefixes[match[1]] = match[2]
+			continue
+		}
+		var terms []rdfTerm
+		for len(terms) < 3 {
+			match := rdfTermPattern.FindStringSubmatch(line)
+			if !assert.NotNil(t, match, "Invalid statement [%s]", scanner.Text()) {
+				return nil
+			}
+			terms = append(terms, parseTerm(match[1]))
+			line = line[len(match[0]):]
+		}
+		assert.Equal(t, ".", line, "Statement should end with a dot")
+		triples = append(triples, triple{terms[0], terms[1], terms[2]})
+	}
+	return triples
+}
+
+// conceptSuggestionFromTriples rebuilds the concept suggestions from the reified annotations
+func conceptSuggestionFromTriples(triples []triple) ConceptSuggestion {
+	objects := map[rdfTerm]map[string][]rdfTerm{}
+	for _, t := range triples {
+		if objects[t.subject] == nil {
+			objects[t.subject] = map[string][]rdfTerm{}
+		}
+		objects[t.subject][t.predicate.iri] = append(objects[t.subject][t.predicate.iri], t.object)
+	}
+	predicates := map[string]string{}
+	for short, uri := range predicateURIs {
+		predicates[uri] = short
+	}
+
+	var statements []string
+	for subject, properties := range objects {
+		if subject.blank != "" && len(properties[rdfSubject]) > 0 {
+			statements = append(statements, subject.blank)
+		}
+	}
+	sort.Slice(statements, func(i, j int) bool {
+		return blankIndex(statements[i]) < blankIndex(statements[j])
+	})
+
+	conceptSuggestion := ConceptSuggestion{Suggestions: []suggestion{}}
+	for _, label := range statements {
+		statement := objects[blank(label)]
+		concept := statement[rdfObject][0]
+		conceptSuggestion.UUID = strings.TrimPrefix(statement[rdfSubject][0].iri, ftContentURIPrefix)
+
+		s := suggestion{Thing: thing{
+			ID:        concept.iri,
+			PrefLabel: objects[concept][skosPrefLabel][0].literal,
+			Predicate: predicates[statement[rdfPredicate][0].iri],
+		}}
+		seen := map[string]bool{}
+		for _, t := range objects[concept][rdfType] {
+			if !seen[t.iri] {
+				s.Thing.Types = append(s.Thing.Types, t.iri)
+				seen[t.iri] = true
+			}
+		}
+		for _, p := range statement[ftProvenance] {
+			prov := provenance{}
+			for _, sc := range objects[p][ftScore] {
+				value, _ := strconv.ParseFloat(objects[sc][ftScoreValue][0].literal, 32)
+				prov.Scores = append(prov.Scores, score{
+					ScoringSystem: objects[sc][ftScoringSystem][0].iri,
+					Value:         float32(value),
+				})
+			}
+			s.Provenance = append(s.Provenance, prov)
+		}
+		conceptSuggestion.Suggestions = append(conceptSuggestion.Suggestions, s)
+	}
+	return conceptSuggestion
+}
+
+func blankIndex(label string) int {
+	var i int
+	fmt.Sscanf(label, "s%d", &i)
+	return i
+}
+
+func TestRDFRoundTrip(t *testing.T) {
+	conceptSuggestion := buildTestConceptSuggestion()
+	conceptSuggestion.Suggestions[1].Thing.PrefLabel = "World \"News\"\\Comment"
+
+	for _, name := range []string{"ntriples", "turtle"} {
+		format, err := selectOutputFormat(name)
+		assert.NoError(t, err)
+		body, err := format.Render(conceptSuggestion)
+		assert.NoError(t, err, name)
+
+		assert.Equal(t, conceptSuggestion, conceptSuggestionFromTriples(parseTriples(t, body)), name)
+	}
+}
+
+func TestNTriples(t *testing.T) {
+	body, _ := nTriplesFormat{}.Render(buildTestConceptSuggestion())
+	lines := strings.Split(string(body), "\n")
+
+	assert.Equal(t, "<http://api.ft.com/content/8bd0194e-e501-11e5-9ef8-8db78aefa51e> <http://www.ft.com/ontology/annotation/mentions> <"+generateID("ON-1")+"> .", lines[0])
+	assert.Contains(t, lines, `_:s0p0s0 <http://www.ft.com/ontology/scoring/value> "0.65"^^<http://www.w3.org/2001/XMLSchema#float> .`)
+}
+
+func TestTurtleAbbreviatesIRIs(t *testing.T) {
+	body, _ := turtleFormat{}.Render(buildTestConceptSuggestion())
+
+	assert.Contains(t, string(body), "@prefix annotation: <http://www.ft.com/ontology/annotation/> .")
+	assert.Contains(t, string(body), "content:8bd0194e-e501-11e5-9ef8-8db78aefa51e annotation:mentions things:")
+	assert.Contains(t, string(body), "rdf:type <http://www.ft.com/ontology/organisation/Organisation> .")
+}
+
+func TestRDFEscapesIRIs(t *testing.T) {
+	conceptSuggestion := buildTestConceptSuggestion()
+	conceptSuggestion.Suggestions[1].Thing.ID = "http://api.ft.com/things/a b> <http://example.com/injected> \"x\" ."
+	escaped := "http://api.ft.com/things/a%20b%3E%20%3Chttp://example.com/injected%3E%20%22x%22%20."
+	assert.Equal(t, escaped, escapeIRI(conceptSuggestion.Suggestions[1].Thing.ID))
+
+	expected := buildTestConceptSuggestion()
+	expected.Suggestions[1].Thing.ID = escaped
+	for _, format := range []OutputFormat{nTriplesFormat{}, turtleFormat{}} {
+		body, err := format.Render(conceptSuggestion)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, conceptSuggestionFromTriples(parseTriples(t, body)), format.ContentType())
+	}
+}
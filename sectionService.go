@@ -14,13 +14,15 @@ func (sectionService SectionService) buildSuggestions(contentRef ContentRef) []s
 	suggestions := []suggestion{}
 
 	for _, value := range sections {
-		suggestions = append(suggestions, buildSuggestion(value, sectionURI, classification))
+		if suggestion, labelled := buildSuggestion(value, sectionURI, classification); labelled {
+			suggestions = append(suggestions, suggestion)
+		}
 	}
 
-	if contentRef.PrimarySection.CanonicalName != "" {
+	if label := normaliseLabel(contentRef.PrimarySection.CanonicalName); label != "" {
 		thing := thing{
-			ID:        generateID(contentRef.PrimarySection.ID),
-			PrefLabel: contentRef.PrimarySection.CanonicalName,
+			ID:        conceptID(contentRef.PrimarySection),
+			PrefLabel: label,
 			Predicate: primaryClassification,
 			Types:     []string{sectionURI},
 		}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Financial-Times/message-queue-gonsumer/consumer"
+)
+
+var errShuttingDown = errors.New("The service is shutting down")
+
+// inFlight tracks the messages being handled, so that they can complete before the service stops
+var inFlight sync.WaitGroup
+
+// Shutdown stops the service in order: it stops being good to go, stops polling the queue, waits for the
+// in-flight messages and flushes any pending output up to the timeout, and finally stops the HTTP server
+type Shutdown struct {
+	consumer     consumer.MessageConsumer
+	timeout      time.Duration
+	flushers     []func(ctx context.Context) error
+	shuttingDown bool
+	lock         sync.RWMutex
+}
+
+// NewShutdown returns a shutdown for the given consumer, waiting up to timeout for the in-flight messages
+// and the flushers, and again up to timeout for the HTTP server
+func NewShutdown(consumer consumer.MessageConsumer, timeout time.Duration) *Shutdown {
+	return &Shutdown{consumer: consumer, timeout: timeout}
+}
+
+// addFlusher adds a function sending any pending output, called once the in-flight messages are done.
+// Its context expires with the shutdown timeout, after which the flusher is not waited for.
+func (s *Shutdown) addFlusher(flush func(ctx context.Context) error) {
+	s.flushers = append(s.flushers, flush)
+}
+
+// Check fails once the shutdown started
+func (s *Shutdown) Check() (string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.shuttingDown {
+		return "", errShuttingDown
+	}
+	return "The service is running", nil
+}
+
+// Run shuts the service down, returning once the HTTP server stopped
+func (s *Shutdown) Run(server *http.Server) {
+	s.lock.Lock()
+	s.shuttingDown = true
+	s.lock.Unlock()
+	infoLogger.Printf("Shutting down")
+
+	deadline := time.Now().Add(s.timeout)
+	if !waitUntil(deadline, s.consumer.Stop) {
+		warnLogger.Printf("Timed out after %v stopping the consumer", s.timeout)
+	}
+	if !waitUntil(deadline, inFlight.Wait) {
+		warnLogger.Printf("Timed out after %v waiting for the in-flight messages", s.timeout)
+	}
+	flushCtx, cancelFlush := context.WithDeadline(context.Background(), deadline)
+	defer cancelFlush()
+	for _, flush := range s.flushers {
+		if err := flushUntilDone(flushCtx, flush); err != nil {
+			errorLogger.Printf("Error flushing pending messages: [%v]", err.Error())
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		errorLogger.Printf("Error shutting down the HTTP server: [%v]", err.Error())
+	}
+	infoLogger.Printf("Shut down")
+}
+
+// flushUntilDone calls flush unless the context is done already, and returns its error or the context
+// error if the context is done first
+func flushUntilDone(ctx context.Context, flush func(ctx context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	result := make(chan error, 1)
+	go func() { result <- flush(ctx) }()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitUntil calls f and returns whether it returned before the deadline
+func waitUntil(deadline time.Time, f func()) bool {
+	done := make(chan struct{})
+	go func() {
+		f()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(deadline.Sub(time.Now())):
+		return false
+	}
+}
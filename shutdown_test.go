@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func startTestServer(t *testing.T) (*http.Server, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := &http.Server{Handler: http.NotFoundHandler()}
+	go server.Serve(listener)
+	return server, listener.Addr().String()
+}
+
+func TestShutdown(t *testing.T) {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	f := &consumerFactory{}
+	c := NewPausableConsumer(f.newConsumer, "")
+	consumed := make(chan struct{})
+	go func() {
+		c.Start()
+		close(consumed)
+	}()
+	<-f.consumer(0).started
+	server, addr := startTestServer(t)
+
+	s := NewShutdown(c, time.Second)
+	flushed := false
+	s.addFlusher(func(context.Context) error {
+		flushed = true
+		return nil
+	})
+	_, err := s.Check()
+	assert.NoError(t, err)
+
+	inFlight.Add(1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		assert.False(t, flushed, "Should flush after the in-flight messages are done")
+		inFlight.Done()
+	}()
+	s.Run(server)
+
+	_, err = s.Check()
+	assert.EqualError(t, err, "The service is shutting down")
+	<-consumed
+	assert.True(t, flushed)
+	_, err = http.Get("http://" + addr + "/__gtg")
+	assert.Error(t, err, "The HTTP server should be shut down")
+}
+
+func TestShutdownTimesOutWaitingForInFlightMessages(t *testing.T) {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	f := &consumerFactory{}
+	c := NewPausableConsumer(f.newConsumer, "")
+	server, _ := startTestServer(t)
+
+	inFlight.Add(1)
+	defer inFlight.Done()
+	start := time.Now()
+	NewShutdown(c, 100*time.Millisecond).Run(server)
+
+	assert.True(t, time.Since(start) < time.Second, "Should give up on the in-flight messages after the timeout")
+}
+
+func TestShutdownGivesFlushersTheRemainingTime(t *testing.T) {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	f := &consumerFactory{}
+	c := NewPausableConsumer(f.newConsumer, "")
+	server, _ := startTestServer(t)
+
+	s := NewShutdown(c, 100*time.Millisecond)
+	stuck := make(chan struct{})
+	defer close(stuck)
+	flushContexts := make(chan context.Context, 1)
+	s.addFlusher(func(ctx context.Context) error {
+		flushContexts <- ctx
+		<-stuck
+		return nil
+	})
+	skipped := true
+	s.addFlusher(func(context.Context) error {
+		skipped = false
+		return nil
+	})
+	start := time.Now()
+	s.Run(server)
+
+	assert.True(t, time.Since(start) < time.Second, "Should give up on the flushers after the timeout")
+	assert.Equal(t, context.DeadlineExceeded, (<-flushContexts).Err())
+	assert.True(t, skipped, "Should skip the flushers once the timeout expired")
+}
+
+func TestGTGFailsWhenShuttingDown(t *testing.T) {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	s := NewShutdown(&mockConsumerInstance{}, time.Second)
+	hc := initializeHealthCheck(true, true)
+	hc.addGTGCheck(s.Check)
+	assert.True(t, hc.GTG().GoodToGo)
+
+	server, _ := startTestServer(t)
+	s.Run(server)
+
+	status := hc.GTG()
+	assert.False(t, status.GoodToGo)
+	assert.Equal(t, "The service is shutting down", status.Message)
+}
@@ -14,13 +14,15 @@ func (specialReportService SpecialReportService) buildSuggestions(contentRef Con
 	suggestions := []suggestion{}
 
 	for _, value := range specialReports {
-		suggestions = append(suggestions, buildSuggestion(value, specialReportURI, classification))
+		if suggestion, labelled := buildSuggestion(value, specialReportURI, classification); labelled {
+			suggestions = append(suggestions, suggestion)
+		}
 	}
 
-	if contentRef.PrimarySection.CanonicalName != "" {
+	if label := normaliseLabel(contentRef.PrimarySection.CanonicalName); label != "" {
 		thing := thing{
-			ID:        generateID(contentRef.PrimarySection.ID),
-			PrefLabel: contentRef.PrimarySection.CanonicalName,
+			ID:        conceptID(contentRef.PrimarySection),
+			PrefLabel: label,
 			Predicate: primaryClassification,
 			Types:     []string{specialReportURI},
 		}
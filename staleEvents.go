@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Financial-Times/message-queue-go-producer/producer"
+	"github.com/Financial-Times/message-queue-gonsumer/consumer"
+)
+
+// The policies for events older than the last processed one for the same content
+const (
+	staleEventsProcess = "process"
+	staleEventsSkip    = "skip"
+	staleEventsRoute   = "route"
+)
+
+const skippedAsStale = "stale"
+
+// StaleEventGuard remembers the contentRef created timestamp of the last processed event per content UUID,
+// for up to maxEntries contents, dropping the oldest remembered content first
+type StaleEventGuard struct {
+	policy     string
+	maxEntries int
+	latest     map[string]time.Time
+	order      []string
+	next       int
+	lock       sync.Mutex
+}
+
+// NewStaleEventGuard returns a guard for the given policy
+func NewStaleEventGuard(policy string, maxEntries int) (*StaleEventGuard, error) {
+	switch policy {
+	case staleEventsProcess, staleEventsSkip, staleEventsRoute:
+	default:
+		return nil, fmt.Errorf("Unknown stale event policy [%s], expected one of %s, %s or %s", policy, staleEventsProcess, staleEventsSkip, staleEventsRoute)
+	}
+	if maxEntries < 0 {
+		return nil, fmt.Errorf("The number of remembered contents can't be negative, got %d", maxEntries)
+	}
+	return &StaleEventGuard{
+		policy:     policy,
+		maxEntries: maxEntries,
+		latest:     map[string]time.Time{},
+		order:      make([]string, maxEntries),
+	}, nil
+}
+
+// isStale returns whether the event was created before the last processed event for the same content.
+// Events are never stale with the process policy, or when their created timestamp can't be parsed.
+func (g *StaleEventGuard) isStale(uuid string, created string) bool {
+	if g.policy == staleEventsProcess {
+		return false
+	}
+	t, ok := parseCreated(created)
+	if !ok {
+		return false
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	latest, found := g.latest[uuid]
+	return found && t.Before(latest)
+}
+
+// processed records the created timestamp of a processed event
+func (g *StaleEventGuard) processed(uuid string, created string) {
+	if g.policy == staleEventsProcess || g.maxEntries <= 0 {
+		return
+	}
+	t, ok := parseCreated(created)
+	if !ok {
+		return
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	latest, found := g.latest[uuid]
+	if !found {
+		delete(g.latest, g.order[g.next])
+		g.order[g.next] = uuid
+		g.next = (g.next + 1) % g.maxEntries
+	}
+	if !found || t.After(latest) {
+		g.latest[uuid] = t
+	}
+}
+
+func parseCreated(created string) (time.Time, bool) {
+	t, err := time.Parse(messageTimestampDateFormat, created)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339Nano, created)
+	}
+	return t, err == nil
+}
+
+// handleStaleEvent skips the stale event, or routes it unchanged to the stale events producer
+func (g *StaleEventGuard) handleStaleEvent(tid string, uuid string, msg consumer.Message, p producer.MessageProducer) {
+	skippedMessages.Add(skippedAsStale, 1)
+	if g.policy != staleEventsRoute {
+		infoLogger.Printf("[%s] Skipping stale event for UUID [%s]", tid, uuid)
+		return
+	}
+	if err := p.SendMessage(uuid, producer.Message{Headers: msg.Headers, Body: msg.Body}); err != nil {
+		errorLogger.Printf("[%s] Error routing stale event for UUID [%s]: [%v]", tid, uuid, err.Error())
+		return
+	}
+	infoLogger.Printf("[%s] Routed stale event for UUID [%s] to the stale events topic", tid, uuid)
+}
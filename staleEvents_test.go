@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/Financial-Times/message-queue-go-producer/producer"
+	"github.com/Financial-Times/message-queue-gonsumer/consumer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaleEvents(t *testing.T) {
+	g, err := NewStaleEventGuard(staleEventsSkip, 10)
+	assert.NoError(t, err)
+
+	assert.False(t, g.isStale("a2b3", "2016-12-29T14:54:10.000Z"), "Nothing processed yet")
+	g.processed("a2b3", "2016-12-29T14:54:10.000Z")
+
+	assert.True(t, g.isStale("a2b3", "2016-12-29T14:54:09.000Z"))
+	assert.False(t, g.isStale("a2b3", "2016-12-29T14:54:10.000Z"), "Republishing the same event is not stale")
+	assert.False(t, g.isStale("a2b3", "2016-12-29T14:55:00Z"))
+	assert.False(t, g.isStale("a2b3", ""), "Events without created timestamp are processed")
+	assert.False(t, g.isStale("c4d5", "2016-12-29T14:54:09.000Z"), "Other contents are independent")
+
+	g.processed("a2b3", "2016-12-29T14:00:00.000Z")
+	assert.True(t, g.isStale("a2b3", "2016-12-29T14:54:09.000Z"), "The latest timestamp is kept")
+}
+
+func TestStaleEventsForgetOldestContent(t *testing.T) {
+	g, _ := NewStaleEventGuard(staleEventsSkip, 2)
+	g.processed("a2b3", "2016-12-29T14:54:10.000Z")
+	g.processed("c4d5", "2016-12-29T14:54:10.000Z")
+	g.processed("e6f7", "2016-12-29T14:54:10.000Z")
+
+	assert.False(t, g.isStale("a2b3", "2016-12-29T14:00:00.000Z"), "The oldest content should be forgotten")
+	assert.True(t, g.isStale("c4d5", "2016-12-29T14:00:00.000Z"))
+	assert.True(t, g.isStale("e6f7", "2016-12-29T14:00:00.000Z"))
+}
+
+func TestStaleEventsBypassedWhenProcessing(t *testing.T) {
+	g, _ := NewStaleEventGuard(staleEventsProcess, 10)
+	g.processed("a2b3", "2016-12-29T14:54:10.000Z")
+	assert.False(t, g.isStale("a2b3", "2016-12-29T14:00:00.000Z"))
+
+	_, err := NewStaleEventGuard("drop", 10)
+	assert.Error(t, err)
+}
+
+func TestStaleEventsRejectsNegativeMaxContents(t *testing.T) {
+	_, err := NewStaleEventGuard(staleEventsSkip, -1)
+	assert.Error(t, err)
+
+	g, err := NewStaleEventGuard(staleEventsSkip, 0)
+	assert.NoError(t, err)
+	g.processed("a2b3", "2016-12-29T14:54:10.000Z")
+	assert.False(t, g.isStale("a2b3", "2016-12-29T14:00:00.000Z"), "Nothing is remembered without contents")
+}
+
+type recordingProducer struct {
+	mockProducerInstance
+	messages []producer.Message
+}
+
+func (p *recordingProducer) SendMessage(uuid string, message producer.Message) error {
+	p.messages = append(p.messages, message)
+	return nil
+}
+
+func TestStaleEventsRouted(t *testing.T) {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	before := expvarInt(skippedMessages.Get(skippedAsStale))
+	p := &recordingProducer{}
+	msg := consumer.Message{Headers: map[string]string{"Message-Id": "1"}, Body: `{"uuid": "a2b3"}`}
+
+	g, _ := NewStaleEventGuard(staleEventsRoute, 10)
+	g.handleStaleEvent("tid_test", "a2b3", msg, p)
+
+	assert.Equal(t, []producer.Message{{Headers: msg.Headers, Body: msg.Body}}, p.messages)
+	assert.Equal(t, before+1, expvarInt(skippedMessages.Get(skippedAsStale)))
+}
+
+func TestUnmarshalCreated(t *testing.T) {
+	metadata, err, _ := unmarshalMetadata([]byte(readmeSampleMetadata))
+	assert.NoError(t, err)
+	assert.Equal(t, "2016-12-29T14:54:10.000Z", metadata.Created)
+}
@@ -14,7 +14,9 @@ func (subjectService SubjectService) buildSuggestions(contentRef ContentRef) []s
 	suggestions := []suggestion{}
 
 	for _, value := range subjects {
-		suggestions = append(suggestions, buildSuggestion(value, subjectURI, classification))
+		if suggestion, labelled := buildSuggestion(value, subjectURI, classification); labelled {
+			suggestions = append(suggestions, suggestion)
+		}
 	}
 
 	return suggestions
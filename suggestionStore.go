@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// storeKeyPattern matches the content UUIDs, which name the files of the store
+var storeKeyPattern = regexp.MustCompile("^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$")
+
+var errInvalidStoreKey = errors.New("Invalid content UUID for suggestion store")
+
+// SuggestionRecord models what we remember about the last suggestions emitted for a piece of content
+type SuggestionRecord struct {
+	ConceptSuggestion ConceptSuggestion `json:"conceptSuggestion"`
+	Headers           map[string]string `json:"headers"`
+	ProcessedAt       string            `json:"processedAt"`
+}
+
+// SuggestionStore defines the operations used to persist and query the emitted suggestions
+type SuggestionStore interface {
+	Save(record SuggestionRecord) error
+	Latest(uuid string) (SuggestionRecord, bool, error)
+	History(uuid string) ([]SuggestionRecord, error)
+}
+
+// FileSuggestionStore is an embedded key-value store keeping one file per content UUID
+// holding its most recent suggestion records, newest first.
+type FileSuggestionStore struct {
+	path        string
+	historySize int
+	lock        sync.RWMutex
+}
+
+// NewFileSuggestionStore creates the store directory if needed and returns a store keeping
+// at most historySize records per content UUID
+func NewFileSuggestionStore(path string, historySize int) (*FileSuggestionStore, error) {
+	if historySize < 1 {
+		historySize = 1
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	return &FileSuggestionStore{path: path, historySize: historySize}, nil
+}
+
+// Save prepends the record to the history of its content UUID, dropping the oldest records over the history size
+func (s *FileSuggestionStore) Save(record SuggestionRecord) error {
+	uuid := record.ConceptSuggestion.UUID
+	if !storeKeyPattern.MatchString(uuid) {
+		return errInvalidStoreKey
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	history, err := s.read(uuid)
+	if err != nil {
+		return err
+	}
+	history = append([]SuggestionRecord{record}, history...)
+	if len(history) > s.historySize {
+		history = history[:s.historySize]
+	}
+	return s.write(uuid, history)
+}
+
+// Latest returns the most recent record for the content UUID, and false if nothing was stored for it
+func (s *FileSuggestionStore) Latest(uuid string) (SuggestionRecord, bool, error) {
+	history, err := s.History(uuid)
+	if err != nil || len(history) == 0 {
+		return SuggestionRecord{}, false, err
+	}
+	return history[0], true, nil
+}
+
+// History returns the stored records for the content UUID, newest first
+func (s *FileSuggestionStore) History(uuid string) ([]SuggestionRecord, error) {
+	if !storeKeyPattern.MatchString(uuid) {
+		return nil, errInvalidStoreKey
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.read(uuid)
+}
+
+func (s *FileSuggestionStore) read(uuid string) ([]SuggestionRecord, error) {
+	data, err := ioutil.ReadFile(s.file(uuid))
+	if os.IsNotExist(err) {
+		return []SuggestionRecord{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var history []SuggestionRecord
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (s *FileSuggestionStore) write(uuid string, history []SuggestionRecord) error {
+	data, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(s.path, uuid+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.file(uuid))
+}
+
+func (s *FileSuggestionStore) file(uuid string) string {
+	return filepath.Join(s.path, uuid+".json")
+}
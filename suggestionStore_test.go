@@ -0,0 +1,105 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSuggestionStore(t *testing.T, historySize int) (*FileSuggestionStore, func()) {
+	dir, err := ioutil.TempDir("", "suggestion-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewFileSuggestionStore(dir, historySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, func() { os.RemoveAll(dir) }
+}
+
+func buildSuggestionRecord(uuid string, messageID string) SuggestionRecord {
+	return SuggestionRecord{
+		ConceptSuggestion: ConceptSuggestion{UUID: uuid, Suggestions: buildConceptSuggestionsWithGenres(1)},
+		Headers:           map[string]string{"Message-Id": messageID},
+		ProcessedAt:       "2017-06-22T11:18:49.000Z",
+	}
+}
+
+func TestSuggestionStoreKeepsNewestFirstUpToHistorySize(t *testing.T) {
+	store, cleanup := newTestSuggestionStore(t, 2)
+	defer cleanup()
+	uuid := "8bd0194e-e501-11e5-9ef8-8db78aefa51e"
+
+	for _, messageID := range []string{"first", "second", "third"} {
+		assert.NoError(t, store.Save(buildSuggestionRecord(uuid, messageID)))
+	}
+
+	history, err := store.History(uuid)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, "third", history[0].Headers["Message-Id"])
+	assert.Equal(t, "second", history[1].Headers["Message-Id"])
+
+	latest, found, err := store.Latest(uuid)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, buildSuggestionRecord(uuid, "third"), latest)
+}
+
+func TestSuggestionStoreUnknownContent(t *testing.T) {
+	store, cleanup := newTestSuggestionStore(t, 2)
+	defer cleanup()
+
+	_, found, err := store.Latest("8bd0194e-e501-11e5-9ef8-8db78aefa51e")
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestSuggestionStoreRejectsInvalidUUID(t *testing.T) {
+	store, cleanup := newTestSuggestionStore(t, 2)
+	defer cleanup()
+
+	assert.Equal(t, errInvalidStoreKey, store.Save(buildSuggestionRecord("../../etc/passwd", "first")))
+	_, err := store.History("../../etc/passwd")
+	assert.Equal(t, errInvalidStoreKey, err)
+	_, _, err = store.Latest("----")
+	assert.Equal(t, errInvalidStoreKey, err)
+}
+
+func TestSuggestionsEndpoints(t *testing.T) {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	store, cleanup := newTestSuggestionStore(t, 2)
+	defer cleanup()
+	uuid := "8bd0194e-e501-11e5-9ef8-8db78aefa51e"
+	assert.NoError(t, store.Save(buildSuggestionRecord(uuid, "first")))
+
+	sh := NewSuggestionsHandler(store)
+	router := mux.NewRouter()
+	router.HandleFunc("/content/{uuid}/suggestions", sh.GetSuggestions)
+	router.HandleFunc("/content/{uuid}/history", sh.GetHistory)
+
+	tests := []struct {
+		name         string
+		url          string
+		expectedCode int
+		expectedBody string
+	}{
+		{"Latest suggestions", "/content/" + uuid + "/suggestions", 200, `"Message-Id":"first"`},
+		{"Suggestion history", "/content/" + uuid + "/history", 200, `[{"conceptSuggestion"`},
+		{"Unknown content", "/content/0000194e-e501-11e5-9ef8-8db78aefa51e/suggestions", 404, `"message"`},
+		{"Invalid UUID suggestions", "/content/not-a-uuid/suggestions", 400, `"Invalid content UUID"`},
+		{"Invalid UUID history", "/content/8bd0194e/history", 400, `"Invalid content UUID"`},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", test.url, nil))
+		assert.Equal(t, test.expectedCode, w.Code, test.name)
+		assert.Contains(t, w.Body.String(), test.expectedBody, test.name)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// SuggestionsHandler serves the stored suggestions for a piece of content
+type SuggestionsHandler struct {
+	store SuggestionStore
+}
+
+// NewSuggestionsHandler returns a handler reading from the given store
+func NewSuggestionsHandler(store SuggestionStore) *SuggestionsHandler {
+	return &SuggestionsHandler{store: store}
+}
+
+// GetSuggestions writes the last suggestion record for the content UUID in the path,
+// or only its concept suggestions when the Accept header asks for another output format
+func (h *SuggestionsHandler) GetSuggestions(w http.ResponseWriter, r *http.Request) {
+	uuid := mux.Vars(r)["uuid"]
+	record, found, err := h.store.Latest(uuid)
+	if err == errInvalidStoreKey {
+		writeJSONMessage(w, http.StatusBadRequest, "Invalid content UUID")
+		return
+	}
+	if err != nil {
+		errorLogger.Printf("Error reading suggestions for UUID [%s]: [%v]", uuid, err.Error())
+		writeJSONMessage(w, http.StatusInternalServerError, "Error reading suggestions")
+		return
+	}
+	if !found {
+		writeJSONMessage(w, http.StatusNotFound, "No suggestions found for content")
+		return
+	}
+	if format, found := negotiateOutputFormat(r.Header.Get("Accept")); found {
+		writeRendered(w, format, record.ConceptSuggestion)
+		return
+	}
+	writeJSON(w, http.StatusOK, record)
+}
+
+// GetHistory writes all the stored suggestion records for the content UUID in the path, newest first
+func (h *SuggestionsHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	uuid := mux.Vars(r)["uuid"]
+	history, err := h.store.History(uuid)
+	if err == errInvalidStoreKey {
+		writeJSONMessage(w, http.StatusBadRequest, "Invalid content UUID")
+		return
+	}
+	if err != nil {
+		errorLogger.Printf("Error reading suggestion history for UUID [%s]: [%v]", uuid, err.Error())
+		writeJSONMessage(w, http.StatusInternalServerError, "Error reading suggestion history")
+		return
+	}
+	if len(history) == 0 {
+		writeJSONMessage(w, http.StatusNotFound, "No suggestions found for content")
+		return
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+func writeRendered(w http.ResponseWriter, format OutputFormat, conceptSuggestion ConceptSuggestion) {
+	body, err := format.Render(conceptSuggestion)
+	if err != nil {
+		errorLogger.Printf("Error rendering suggestions for UUID [%s]: [%v]", conceptSuggestion.UUID, err.Error())
+		writeJSONMessage(w, http.StatusInternalServerError, "Error rendering suggestions")
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func writeJSONMessage(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		errorLogger.Printf("Error writing response: [%v]", err.Error())
+	}
+}
@@ -27,7 +27,32 @@ func generateID(cmrTermID string) string {
 	return "http://api.ft.com/things/" + NewNameUUIDFromBytes([]byte(cmrTermID)).String()
 }
 
+// conceptID returns the thing ID for the term, preferring a concordance override over a generated ID
+func conceptID(t term) string {
+	if concordances != nil {
+		if thing, found := concordances.Resolve(t); found {
+			return thing
+		}
+	}
+	return generateID(t.ID)
+}
+
+// indexTags groups the tags of the content by lower case taxonomy once, for all the handlers,
+// keeping the order of the tags
+func indexTags(contentRef ContentRef) ContentRef {
+	contentRef.taxonomyTags = map[string][]tag{}
+	for _, tag := range contentRef.TagHolder.Tags {
+		taxonomy := strings.ToLower(tag.Term.Taxonomy)
+		contentRef.taxonomyTags[taxonomy] = append(contentRef.taxonomyTags[taxonomy], tag)
+	}
+	return contentRef
+}
+
+// extractTags returns the tags of the taxonomy, from the tag index when the content was indexed
 func extractTags(wantedTagName string, contentRef ContentRef) []tag {
+	if contentRef.taxonomyTags != nil {
+		return contentRef.taxonomyTags[strings.ToLower(wantedTagName)]
+	}
 	var wantedTags []tag
 	for _, tag := range contentRef.TagHolder.Tags {
 		if strings.EqualFold(tag.Term.Taxonomy, wantedTagName) {
@@ -37,7 +62,12 @@ func extractTags(wantedTagName string, contentRef ContentRef) []tag {
 	return wantedTags
 }
 
-func buildSuggestion(tag tag, thingType string, predicate string) suggestion {
+// buildSuggestion builds the suggestion of a tag, returning false when the tag has no label once normalised
+func buildSuggestion(tag tag, thingType string, predicate string) (suggestion, bool) {
+	label := normaliseLabel(tag.Term.CanonicalName)
+	if label == "" {
+		return suggestion{}, false
+	}
 	relevance := score{
 		ScoringSystem: relevanceURI,
 		Value:         transformScore(tag.TagScore.Relevance),
@@ -53,11 +83,11 @@ func buildSuggestion(tag tag, thingType string, predicate string) suggestion {
 		},
 	}
 	thing := thing{
-		ID:        generateID(tag.Term.ID),
-		PrefLabel: tag.Term.CanonicalName,
+		ID:        conceptID(tag.Term),
+		PrefLabel: label,
 		Predicate: predicate,
 		Types:     []string{thingType},
 	}
 
-	return suggestion{Thing: thing, Provenance: provenances}
+	return suggestion{Thing: thing, Provenance: provenances}, true
 }
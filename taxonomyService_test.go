@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/base64"
 	"fmt"
 	"testing"
 
@@ -883,3 +884,107 @@ var peopleNames = [...]string{"Person 1", "Person 2"}
 var peopleTMEIDs = [...]string{"Person-1-TME", "Person-2-TME"}
 var authorNames = [...]string{"Author 1", "Author 2"}
 var authorTMEIDs = [...]string{"Author-1-TME", "Author-2-TME"}
+
+// realisticMessages are metadata documents as published, from a few to hundreds of tags
+func realisticMessages(t testing.TB) map[string]ContentRef {
+	methodeXML, _ := base64.StdEncoding.DecodeString(validUTF8Metadata)
+	documents := map[string][]byte{
+		"readme":  []byte(readmeSampleMetadata),
+		"methode": methodeXML,
+	}
+	messages := map[string]ContentRef{}
+	for name, document := range documents {
+		metadata, err, _ := unmarshalMetadata(document)
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages[name] = metadata
+	}
+
+	large := messages["methode"]
+	var largeTags []tag
+	for i := 0; i < 20; i++ {
+		largeTags = append(largeTags, large.TagHolder.Tags...)
+	}
+	large.TagHolder.Tags = largeTags
+	messages["large"] = large
+	return messages
+}
+
+func TestIndexedTagsBuildTheSameSuggestions(t *testing.T) {
+	setupTaxonomyHandlers()
+	for name, metadata := range realisticMessages(t) {
+		indexed := indexTags(metadata)
+		for taxonomy, handler := range taxonomyHandlers {
+			assert.Equal(t, handler.buildSuggestions(metadata), handler.buildSuggestions(indexed), "%s %s", name, taxonomy)
+		}
+	}
+}
+
+func TestIndexedTagsIgnoreTaxonomyCase(t *testing.T) {
+	contentRef := indexTags(ContentRef{TagHolder: tags{Tags: []tag{
+		{Term: term{Taxonomy: "Topics", ID: "1"}},
+		{Term: term{Taxonomy: "ON", ID: "2"}},
+		{Term: term{Taxonomy: "topics", ID: "3"}},
+	}}})
+
+	assert.Equal(t, []tag{{Term: term{Taxonomy: "Topics", ID: "1"}}, {Term: term{Taxonomy: "topics", ID: "3"}}}, extractTags("TOPICS", contentRef))
+	assert.Equal(t, []tag{{Term: term{Taxonomy: "ON", ID: "2"}}}, extractTags("on", contentRef))
+	assert.Nil(t, extractTags("PN", contentRef))
+}
+
+func benchmarkBuildSuggestions(b *testing.B, index bool) {
+	setupTaxonomyHandlers()
+	for name, metadata := range realisticMessages(b) {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				contentRef := metadata
+				if index {
+					contentRef = indexTags(metadata)
+				}
+				for _, handler := range taxonomyHandlers {
+					handler.buildSuggestions(contentRef)
+				}
+			}
+		})
+	}
+}
+
+func benchmarkExtractTags(b *testing.B, index bool) {
+	setupTaxonomyHandlers()
+	taxonomies := []string{"subjects", "sections", "topics", "gl", "genres", "specialReports",
+		"alphavilleSeriesClassification", "ON", "PN", "Authors", "Brands"}
+	for name, metadata := range realisticMessages(b) {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				contentRef := metadata
+				if index {
+					contentRef = indexTags(metadata)
+				}
+				for _, taxonomy := range taxonomies {
+					extractTags(taxonomy, contentRef)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkExtractTagsScanningTags is the baseline of BenchmarkExtractTags, scanning all the tags for each taxonomy
+func BenchmarkExtractTagsScanningTags(b *testing.B) {
+	benchmarkExtractTags(b, false)
+}
+
+func BenchmarkExtractTags(b *testing.B) {
+	benchmarkExtractTags(b, true)
+}
+
+// BenchmarkBuildSuggestionsScanningTags is the baseline of BenchmarkBuildSuggestions, with each handler scanning all the tags
+func BenchmarkBuildSuggestionsScanningTags(b *testing.B) {
+	benchmarkBuildSuggestions(b, false)
+}
+
+func BenchmarkBuildSuggestions(b *testing.B) {
+	benchmarkBuildSuggestions(b, true)
+}
@@ -14,13 +14,15 @@ func (topicService TopicService) buildSuggestions(contentRef ContentRef) []sugge
 	suggestions := []suggestion{}
 
 	for _, value := range topics {
-		suggestions = append(suggestions, buildSuggestion(value, topicURI, conceptMajorMentions))
+		if suggestion, labelled := buildSuggestion(value, topicURI, conceptMajorMentions); labelled {
+			suggestions = append(suggestions, suggestion)
+		}
 	}
 
-	if contentRef.PrimaryTheme.CanonicalName != "" {
+	if label := normaliseLabel(contentRef.PrimaryTheme.CanonicalName); label != "" {
 		thing := thing{
-			ID:        generateID(contentRef.PrimaryTheme.ID),
-			PrefLabel: contentRef.PrimaryTheme.CanonicalName,
+			ID:        conceptID(contentRef.PrimaryTheme),
+			PrefLabel: label,
 			Predicate: about,
 			Types:     []string{topicURI},
 		}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// suggestConcepts builds the concept suggestions for the metadata of the content, with the mapping profile
+// selected by the headers and the metadata
+func suggestConcepts(tid string, uuid string, headers map[string]string, metadata ContentRef) ConceptSuggestion {
+	metadata = indexTags(metadata)
+	profile := mappingProfiles.selectProfile(headers, metadata)
+	infoLogger.Printf("[%s] Using mapping profile [%s]", tid, profile.Name)
+
+	suggestions := []suggestion{}
+	for key, value := range profile.Handlers {
+		infoLogger.Printf("[%s] Processing taxonomy [%s]", tid, key)
+		suggestions = append(suggestions, value.buildSuggestions(metadata)...)
+	}
+	return ConceptSuggestion{UUID: uuid, Suggestions: suggestions}
+}
+
+// transformMetadata builds the concept suggestions for metadata in XML or JSON, recognised as for the source messages
+func transformMetadata(tid string, uuid string, headers map[string]string, metadataValue []byte) (ConceptSuggestion, error) {
+	format := metadataFormat(headers["Content-Type"], metadataValue)
+	metadata, err, _ := unmarshalContentRef(format, metadataValue)
+	if err != nil {
+		return ConceptSuggestion{}, fmt.Errorf("Error unmarshalling metadata %s: %v", strings.ToUpper(format), err)
+	}
+	return suggestConcepts(tid, uuid, headers, metadata), nil
+}
+
+// Transform writes the concept suggestions for the metadata in the request body, for the content UUID in the path.
+// They are written in the output format asked by the Accept header, in JSON by default.
+func Transform(w http.ResponseWriter, r *http.Request) {
+	uuid := mux.Vars(r)["uuid"]
+	if !storeKeyPattern.MatchString(uuid) {
+		writeJSONMessage(w, http.StatusBadRequest, "Invalid content UUID")
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeJSONMessage(w, http.StatusBadRequest, "Error reading metadata")
+		return
+	}
+	headers := map[string]string{}
+	for name := range r.Header {
+		headers[name] = r.Header.Get(name)
+	}
+	conceptSuggestion, err := transformMetadata(headers["X-Request-Id"], uuid, headers, body)
+	if err != nil {
+		writeJSONMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if format, found := negotiateOutputFormat(r.Header.Get("Accept")); found {
+		writeRendered(w, format, conceptSuggestion)
+		return
+	}
+	writeJSON(w, http.StatusOK, conceptSuggestion)
+}
+
+// runTransform writes the concept suggestions for the metadata read from in, in the given format
+func runTransform(in io.Reader, out io.Writer, uuid string, contentType string, format OutputFormat) error {
+	metadataValue, err := ioutil.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	conceptSuggestion, err := transformMetadata("transform", uuid, map[string]string{"Content-Type": contentType}, metadataValue)
+	if err != nil {
+		return err
+	}
+	body, err := format.Render(conceptSuggestion)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(body)
+	return err
+}
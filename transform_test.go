@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+const transformTestUUID = "8bd0194e-e501-11e5-9ef8-8db78aefa51e"
+
+func setupTransform() *mux.Router {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	setupTaxonomyHandlers()
+	mappingProfiles, _ = newMappingProfiles("", taxonomyHandlers)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/transform/{uuid}", Transform).Methods("POST")
+	return router
+}
+
+func prefLabels(conceptSuggestion ConceptSuggestion) map[string]bool {
+	labels := map[string]bool{}
+	for _, s := range conceptSuggestion.Suggestions {
+		labels[s.Thing.PrefLabel] = true
+	}
+	return labels
+}
+
+func TestTransform(t *testing.T) {
+	router := setupTransform()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/transform/"+transformTestUUID, strings.NewReader(readmeSampleMetadata)))
+	assert.Equal(t, 200, w.Code)
+	var conceptSuggestion ConceptSuggestion
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &conceptSuggestion))
+	assert.Equal(t, transformTestUUID, conceptSuggestion.UUID)
+	assert.True(t, prefLabels(conceptSuggestion)["Global politics"], "Expected the topic in %v", conceptSuggestion)
+
+	r := httptest.NewRequest("POST", "/transform/"+transformTestUUID, strings.NewReader(readmeSampleMetadata))
+	r.Header.Set("Accept", "application/n-triples")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "application/n-triples", w.Header().Get("Content-Type"))
+	assert.Equal(t, prefLabels(conceptSuggestion), prefLabels(conceptSuggestionFromTriples(parseTriples(t, w.Body.Bytes()))))
+}
+
+func TestTransformRejectsInvalidRequests(t *testing.T) {
+	router := setupTransform()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/transform/3505101", strings.NewReader(readmeSampleMetadata)))
+	assert.Equal(t, 400, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/transform/"+transformTestUUID, strings.NewReader("<contentRef")))
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestRunTransform(t *testing.T) {
+	setupTransform()
+
+	var out bytes.Buffer
+	assert.NoError(t, runTransform(strings.NewReader(readmeSampleMetadata), &out, transformTestUUID, "application/xml", turtleFormat{}))
+	conceptSuggestion := conceptSuggestionFromTriples(parseTriples(t, out.Bytes()))
+	assert.Equal(t, transformTestUUID, conceptSuggestion.UUID)
+	assert.True(t, prefLabels(conceptSuggestion)["Comment"], "Expected the genre in %v", conceptSuggestion)
+
+	assert.Error(t, runTransform(strings.NewReader("{"), &out, transformTestUUID, "", jsonFormat{}))
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"expvar"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The strategies repairing metadata with invalid UTF-8
+const (
+	utf8RepairReplace     = "replace"
+	utf8RepairWindows1252 = "windows1252"
+)
+
+// utf8Repair is the strategy repairing metadata with invalid UTF-8 before parsing it again.
+// Such metadata is dropped when empty.
+var utf8Repair string
+
+// repairedMessages counts the source messages whose metadata was repaired, by strategy
+var repairedMessages = expvar.NewMap("repairedMessages")
+
+// maxRepairedOffsets is the number of repaired offsets listed in the UTF8-Repaired-Offsets header
+const maxRepairedOffsets = 20
+
+// windows1252 maps the bytes 0x80 to 0x9F of Windows-1252 to runes. The bytes above are the same in
+// Latin-1 and Unicode, and the five bytes undefined in Windows-1252 are replaced.
+var windows1252 = [32]rune{
+	'€', utf8.RuneError, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', utf8.RuneError, 'Ž', utf8.RuneError,
+	utf8.RuneError, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', utf8.RuneError, 'ž', 'Ÿ',
+}
+
+func validateUTF8Repair(strategy string) error {
+	switch strategy {
+	case "", utf8RepairReplace, utf8RepairWindows1252:
+		return nil
+	default:
+		return fmt.Errorf("Unknown UTF-8 repair [%s], expected %s or %s", strategy, utf8RepairReplace, utf8RepairWindows1252)
+	}
+}
+
+// repairUTF8 returns the data with each invalid UTF-8 byte replaced by U+FFFD, or reinterpreted as
+// Windows-1252, and the offsets of the bytes repaired in data
+func repairUTF8(strategy string, data []byte) ([]byte, []int) {
+	var repaired bytes.Buffer
+	var offsets []int
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size == 1 {
+			offsets = append(offsets, i)
+			repaired.WriteRune(repairByte(strategy, data[i]))
+		} else {
+			repaired.Write(data[i : i+size])
+		}
+		i += size
+	}
+	return repaired.Bytes(), offsets
+}
+
+func repairByte(strategy string, b byte) rune {
+	if strategy != utf8RepairWindows1252 {
+		return utf8.RuneError
+	}
+	if b >= 0x80 && b <= 0x9F {
+		return windows1252[b-0x80]
+	}
+	return rune(b)
+}
+
+// repairContentRef repairs the invalid UTF-8 of the metadata and unmarshals it again
+func repairContentRef(format string, metadata []byte) (ContentRef, []int, error) {
+	repaired, offsets := repairUTF8(utf8Repair, metadata)
+	contentRef, err, _ := unmarshalContentRef(format, repaired)
+	if err != nil {
+		return contentRef, offsets, err
+	}
+	repairedMessages.Add(utf8Repair, 1)
+	return contentRef, offsets, nil
+}
+
+// addRepairHeaders marks the concept suggestions of repaired metadata with the repair strategy
+// and the first repaired offsets
+func addRepairHeaders(headers map[string]string, offsets []int) {
+	var listed []string
+	for i, offset := range offsets {
+		if i == maxRepairedOffsets {
+			listed = append(listed, "...")
+			break
+		}
+		listed = append(listed, strconv.Itoa(offset))
+	}
+	headers["UTF8-Repaired"] = utf8Repair
+	headers["UTF8-Repaired-Offsets"] = strings.Join(listed, ",")
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepairUTF8(t *testing.T) {
+	data := []byte("Luiz In\xe1cio \x93Lula\x94 da Silva, S\xc3\xa3o Paulo")
+
+	replaced, offsets := repairUTF8(utf8RepairReplace, data)
+	assert.Equal(t, "Luiz In�cio �Lula� da Silva, São Paulo", string(replaced))
+	assert.Equal(t, []int{7, 12, 17}, offsets)
+
+	reinterpreted, offsets := repairUTF8(utf8RepairWindows1252, data)
+	assert.Equal(t, "Luiz Inácio “Lula” da Silva, São Paulo", string(reinterpreted))
+	assert.Equal(t, []int{7, 12, 17}, offsets)
+
+	undefined, _ := repairUTF8(utf8RepairWindows1252, []byte("\x81"))
+	assert.Equal(t, "�", string(undefined))
+
+	valid, offsets := repairUTF8(utf8RepairWindows1252, []byte("São Paulo"))
+	assert.Equal(t, "São Paulo", string(valid))
+	assert.Empty(t, offsets)
+}
+
+func TestRepairContentRef(t *testing.T) {
+	defer func() { utf8Repair = "" }()
+	metadataXML, _ := base64.StdEncoding.DecodeString(invalidUTF8Metadata)
+	before := expvarInt(repairedMessages.Get(utf8RepairWindows1252))
+
+	utf8Repair = utf8RepairWindows1252
+	metadata, offsets, err := repairContentRef(metadataFormatXML, metadataXML)
+	assert.NoError(t, err)
+	assert.Len(t, offsets, 1)
+	assert.Equal(t, byte(0xe1), metadataXML[offsets[0]])
+	var names []string
+	for _, tag := range metadata.TagHolder.Tags {
+		names = append(names, tag.Term.CanonicalName)
+	}
+	assert.Contains(t, names, "Luiz Inácio Lula da Silva")
+	assert.Equal(t, before+1, expvarInt(repairedMessages.Get(utf8RepairWindows1252)))
+}
+
+func TestAddRepairHeaders(t *testing.T) {
+	defer func() { utf8Repair = "" }()
+	utf8Repair = utf8RepairReplace
+
+	headers := map[string]string{}
+	addRepairHeaders(headers, []int{3, 17})
+	assert.Equal(t, map[string]string{"UTF8-Repaired": "replace", "UTF8-Repaired-Offsets": "3,17"}, headers)
+
+	offsets := make([]int, maxRepairedOffsets+5)
+	addRepairHeaders(headers, offsets)
+	listed := strings.Split(headers["UTF8-Repaired-Offsets"], ",")
+	assert.Len(t, listed, maxRepairedOffsets+1)
+	assert.Equal(t, "...", listed[maxRepairedOffsets])
+}
+
+func TestInvalidUTF8Repair(t *testing.T) {
+	assert.NoError(t, validateUTF8Repair(""))
+	assert.EqualError(t, validateUTF8Repair("ignore"), "Unknown UTF-8 repair [ignore], expected replace or windows1252")
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+
+	"github.com/Financial-Times/message-queue-gonsumer/consumer"
+)
+
+var errWorkerPoolClosed = errors.New("Worker pool is closed, the messages were not handled")
+
+// WorkerPool handles batches of messages on a fixed number of workers, sharded by content UUID
+// so that the messages for one content are handled in order, and the ones for different contents in parallel
+type WorkerPool struct {
+	queues  []chan workItem
+	handler func(consumer.Message, MetadataPublishEvent)
+	workers sync.WaitGroup
+	// closed is set by Close, and read-locked while a batch is queued
+	closed bool
+	lock   sync.RWMutex
+}
+
+// workItem is a message of a batch, with the wait group of the batch done once the message is handled
+type workItem struct {
+	msg     consumer.Message
+	event   MetadataPublishEvent
+	handled *sync.WaitGroup
+}
+
+// NewWorkerPool starts size workers, each with a queue of queueSize messages
+func NewWorkerPool(size int, queueSize int, handler func(consumer.Message, MetadataPublishEvent)) *WorkerPool {
+	w := &WorkerPool{handler: handler}
+	for i := 0; i < size; i++ {
+		queue := make(chan workItem, queueSize)
+		w.queues = append(w.queues, queue)
+		w.workers.Add(1)
+		go w.work(queue)
+	}
+	return w
+}
+
+func (w *WorkerPool) work(queue chan workItem) {
+	defer w.workers.Done()
+	for item := range queue {
+		w.handler(item.msg, item.event)
+		item.handled.Done()
+	}
+}
+
+// Handle queues the messages, in order, on the workers for the content UUIDs of their events, blocking
+// while a queue is full, and returns once they are all handled so that the consumer only commits the batch
+// once handled. It fails once the pool is closed.
+func (w *WorkerPool) Handle(msgs []consumer.Message, events []MetadataPublishEvent) error {
+	var handled sync.WaitGroup
+	w.lock.RLock()
+	if w.closed {
+		w.lock.RUnlock()
+		return errWorkerPoolClosed
+	}
+	for i, msg := range msgs {
+		handled.Add(1)
+		w.queues[w.shard(events[i].UUID)] <- workItem{msg: msg, event: events[i], handled: &handled}
+	}
+	w.lock.RUnlock()
+	handled.Wait()
+	return nil
+}
+
+// shard returns the worker for the content UUID
+func (w *WorkerPool) shard(uuid string) int {
+	h := fnv.New32a()
+	h.Write([]byte(uuid))
+	return int(h.Sum32() % uint32(len(w.queues)))
+}
+
+// Close stops the workers once the queued messages are handled, or returns the context error
+// if the context is done first. No batch can be handled afterwards.
+func (w *WorkerPool) Close(ctx context.Context) error {
+	w.lock.Lock()
+	if !w.closed {
+		w.closed = true
+		for _, queue := range w.queues {
+			close(queue)
+		}
+	}
+	w.lock.Unlock()
+	stopped := make(chan struct{})
+	go func() {
+		w.workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// QueueDepths returns the number of messages waiting on each worker
+func (w *WorkerPool) QueueDepths() []int {
+	depths := make([]int, len(w.queues))
+	for i, queue := range w.queues {
+		depths[i] = len(queue)
+	}
+	return depths
+}
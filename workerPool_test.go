@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/message-queue-gonsumer/consumer"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildBatch(uuids []string, count int) ([]consumer.Message, []MetadataPublishEvent) {
+	var msgs []consumer.Message
+	var events []MetadataPublishEvent
+	for seq := 0; seq < count; seq++ {
+		for _, uuid := range uuids {
+			msgs = append(msgs, consumer.Message{Headers: map[string]string{"seq": fmt.Sprint(seq)}})
+			events = append(events, MetadataPublishEvent{UUID: uuid})
+		}
+	}
+	return msgs, events
+}
+
+func TestWorkerPoolKeepsOrderPerUUID(t *testing.T) {
+	var lock sync.Mutex
+	handled := map[string][]int{}
+	w := NewWorkerPool(4, 10, func(msg consumer.Message, event MetadataPublishEvent) {
+		time.Sleep(time.Millisecond)
+		lock.Lock()
+		defer lock.Unlock()
+		var seq int
+		fmt.Sscan(msg.Headers["seq"], &seq)
+		handled[event.UUID] = append(handled[event.UUID], seq)
+	})
+
+	uuids := []string{"a2b3", "c4d5", "e6f7", "0819"}
+	assert.NoError(t, w.Handle(buildBatch(uuids, 20)))
+	assert.NoError(t, w.Close(context.Background()))
+
+	for _, uuid := range uuids {
+		assert.Len(t, handled[uuid], 20)
+		for i, seq := range handled[uuid] {
+			assert.Equal(t, i, seq, "Messages for [%s] should be handled in order", uuid)
+		}
+	}
+}
+
+func TestWorkerPoolHandlesContentsInParallel(t *testing.T) {
+	var started sync.WaitGroup
+	started.Add(2)
+	w := NewWorkerPool(2, 10, func(consumer.Message, MetadataPublishEvent) {
+		started.Done()
+		started.Wait()
+	})
+	defer w.Close(context.Background())
+
+	uuids := []string{"a2b3"}
+	for len(uuids) < 2 {
+		if uuid := fmt.Sprint(len(uuids)); w.shard(uuid) != w.shard(uuids[0]) {
+			uuids = append(uuids, uuid)
+		}
+	}
+	assert.NoError(t, w.Handle(buildBatch(uuids, 1)), "Both messages should be handled at the same time")
+}
+
+func TestWorkerPoolHandleWaitsUntilHandled(t *testing.T) {
+	handled := 0
+	w := NewWorkerPool(2, 5, func(consumer.Message, MetadataPublishEvent) {
+		time.Sleep(10 * time.Millisecond)
+		handled++
+	})
+
+	assert.NoError(t, w.Handle(buildBatch([]string{"a2b3"}, 3)))
+	assert.Equal(t, 3, handled, "The consumer should only commit the batch once handled")
+	assert.NoError(t, w.Close(context.Background()))
+}
+
+func TestWorkerPoolFailsAfterClose(t *testing.T) {
+	w := NewWorkerPool(2, 5, func(consumer.Message, MetadataPublishEvent) {
+		t.Error("No message should be handled after Close")
+	})
+
+	assert.NoError(t, w.Close(context.Background()))
+	assert.NoError(t, w.Close(context.Background()), "Closing again should do nothing")
+	assert.Equal(t, errWorkerPoolClosed, w.Handle(buildBatch([]string{"a2b3"}, 1)))
+}
+
+func TestWorkerPoolQueueDepths(t *testing.T) {
+	handling := make(chan struct{}, 4)
+	release := make(chan struct{})
+	w := NewWorkerPool(2, 5, func(consumer.Message, MetadataPublishEvent) {
+		handling <- struct{}{}
+		<-release
+	})
+
+	handled := make(chan error)
+	go func() { handled <- w.Handle(buildBatch([]string{"a2b3"}, 4)) }()
+	<-handling
+	for depths := w.QueueDepths(); depths[0]+depths[1] < 3; depths = w.QueueDepths() {
+		time.Sleep(time.Millisecond)
+	}
+	depths := w.QueueDepths()
+	assert.Len(t, depths, 2)
+	assert.Equal(t, 3, depths[0]+depths[1], "One message is being handled")
+
+	close(release)
+	assert.NoError(t, <-handled)
+	assert.NoError(t, w.Close(context.Background()))
+	assert.Equal(t, []int{0, 0}, w.QueueDepths())
+}
+
+func TestWorkerPoolCloseStopsWaitingWhenTheContextIsDone(t *testing.T) {
+	handling := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	w := NewWorkerPool(1, 5, func(consumer.Message, MetadataPublishEvent) {
+		close(handling)
+		<-release
+	})
+	go w.Handle(buildBatch([]string{"a2b3"}, 1))
+	<-handling
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, w.Close(ctx))
+}
+
+func TestConsumeBatchSkipsDuplicatesAndFilteredMessages(t *testing.T) {
+	initLogs(ioutil.Discard, ioutil.Discard, ioutil.Discard, ioutil.Discard)
+	defer func(d *DedupCache) { dedupCache = d }(dedupCache)
+	dedupCache = NewDedupCache(time.Minute, 10)
+	messageFilter = MessageFilter{DeniedMessageTypes: []string{"synthetic-publication"}}
+	defer func() { messageFilter = MessageFilter{} }()
+
+	var handled []string
+	workerPool = NewWorkerPool(2, 5, func(msg consumer.Message, event MetadataPublishEvent) {
+		handled = append(handled, msg.Headers["Message-Id"]+" "+event.UUID)
+	})
+	defer func() { workerPool = nil }()
+
+	consumeBatch([]consumer.Message{
+		{Headers: map[string]string{"Message-Id": "1"}, Body: `{"uuid": "a2b3"}`},
+		{Headers: map[string]string{"Message-Id": "1"}, Body: `{"uuid": "a2b3"}`},
+		{Headers: map[string]string{"Message-Id": "2", "Message-Type": "synthetic-publication"}, Body: `{"uuid": "a2b3"}`},
+		{Headers: map[string]string{"Message-Id": "3"}, Body: `not json`},
+		{Headers: map[string]string{"Message-Id": "4"}, Body: `{"uuid": "a2b3"}`},
+	})
+	assert.NoError(t, workerPool.Close(context.Background()))
+
+	assert.Equal(t, []string{"1 a2b3", "4 a2b3"}, handled)
+}
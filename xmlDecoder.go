@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// XMLLimits bounds the metadata XML accepted, 0 meaning unlimited
+type XMLLimits struct {
+	MaxBytes    int
+	MaxElements int
+	MaxDepth    int
+}
+
+// xmlLimits are the limits of the metadata XML decoded
+var xmlLimits = XMLLimits{MaxBytes: 16 << 20, MaxElements: 100000, MaxDepth: 64}
+
+// xmlLocationError locates an error in the metadata XML
+type xmlLocationError struct {
+	err          error
+	offset       int64
+	line, column int
+}
+
+func (e *xmlLocationError) Error() string {
+	return fmt.Sprintf("%v at line %d, column %d (byte offset %d)", e.err, e.line, e.column, e.offset)
+}
+
+func locateXMLError(data []byte, offset int64, err error) error {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := int(offset) - bytes.LastIndexByte(before, '\n')
+	if syntaxErr, ok := err.(*xml.SyntaxError); ok {
+		err = fmt.Errorf("XML syntax error: %s", syntaxErr.Msg)
+	}
+	return &xmlLocationError{err: err, offset: offset, line: line, column: column}
+}
+
+// contentRefDecoder decodes ContentRef from the XML tokens, as xml.Unmarshal would but without
+// reflection or keeping the document. The elements and attributes are matched on their local names.
+type contentRefDecoder struct {
+	decoder    *xml.Decoder
+	limits     XMLLimits
+	contentRef ContentRef
+	// stack holds the names of the open elements, from the root
+	stack    []xml.Name
+	elements int
+	// offset is where the last token read starts, or where the decoder stopped on a malformed token
+	offset int64
+	// term is the term whose elements are decoded, text collects its canonical name
+	term *term
+	text []byte
+}
+
+// decodeContentRef decodes the root element of the metadata XML into a ContentRef, stopping
+// at the first malformed token or exceeded limit
+func decodeContentRef(data []byte, limits XMLLimits) (ContentRef, error) {
+	if limits.MaxBytes > 0 && len(data) > limits.MaxBytes {
+		return ContentRef{}, fmt.Errorf("Metadata XML has %d bytes, more than %d", len(data), limits.MaxBytes)
+	}
+	d := &contentRefDecoder{decoder: xml.NewDecoder(bytes.NewReader(data)), limits: limits}
+	err := d.decode()
+	if err != nil && err != io.EOF {
+		return d.contentRef, locateXMLError(data, d.offset, err)
+	}
+	return d.contentRef, err
+}
+
+func (d *contentRefDecoder) decode() error {
+	for {
+		d.offset = d.decoder.InputOffset()
+		token, err := d.decoder.RawToken()
+		if err == io.EOF && len(d.stack) > 0 {
+			return &xml.SyntaxError{Msg: "unexpected EOF"}
+		}
+		if err != nil {
+			d.offset = d.decoder.InputOffset()
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if err := d.start(t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			if err := d.end(t); err != nil {
+				return err
+			}
+			if len(d.stack) == 0 {
+				return nil
+			}
+		case xml.CharData:
+			if d.collectingText() {
+				d.text = append(d.text, t...)
+			}
+		}
+	}
+}
+
+func (d *contentRefDecoder) start(t xml.StartElement) error {
+	d.stack = append(d.stack, t.Name)
+	d.elements++
+	if d.limits.MaxElements > 0 && d.elements > d.limits.MaxElements {
+		return fmt.Errorf("Metadata XML has more than %d elements", d.limits.MaxElements)
+	}
+	if d.limits.MaxDepth > 0 && len(d.stack) > d.limits.MaxDepth {
+		return fmt.Errorf("Metadata XML is nested deeper than %d elements", d.limits.MaxDepth)
+	}
+
+	switch {
+	case len(d.stack) == 1:
+		d.contentRef.Created = attrValue(t, "created", d.contentRef.Created)
+	case d.at("tags", "tag"):
+		d.contentRef.TagHolder.Tags = append(d.contentRef.TagHolder.Tags, tag{})
+	case d.at("tags", "tag", "term"):
+		return d.startTerm(t, &d.currentTag().Term)
+	case d.at("tags", "tag", "score"):
+		score := &d.currentTag().TagScore
+		var err error
+		if score.Confidence, err = intAttrValue(t, "confidence", score.Confidence); err != nil {
+			return err
+		}
+		score.Relevance, err = intAttrValue(t, "relevance", score.Relevance)
+		return err
+	case d.at("primarySection"):
+		return d.startTerm(t, &d.contentRef.PrimarySection)
+	case d.at("primaryTheme"):
+		return d.startTerm(t, &d.contentRef.PrimaryTheme)
+	case d.at("externalReferences", "reference"):
+		d.contentRef.ExternalReferences.References = append(d.contentRef.ExternalReferences.References, externalReference{
+			ExternalSource: attrValue(t, "externalSource", ""),
+			ExternalID:     attrValue(t, "externalId", ""),
+		})
+	case d.collectingText():
+		d.text = d.text[:0]
+	}
+	return nil
+}
+
+func (d *contentRefDecoder) startTerm(t xml.StartElement, current *term) error {
+	d.term = current
+	current.Taxonomy = attrValue(t, "taxonomy", current.Taxonomy)
+	current.ExternalTermID = attrValue(t, "externalTermId", current.ExternalTermID)
+	current.ID = attrValue(t, "id", current.ID)
+	return nil
+}
+
+func (d *contentRefDecoder) end(t xml.EndElement) error {
+	open := d.stack[len(d.stack)-1]
+	if open != t.Name {
+		return &xml.SyntaxError{Msg: "element <" + qualifiedName(open) + "> closed by </" + qualifiedName(t.Name) + ">"}
+	}
+	if d.collectingText() {
+		d.term.CanonicalName = string(d.text)
+	}
+	d.stack = d.stack[:len(d.stack)-1]
+	return nil
+}
+
+// at reports whether the open elements below the root have the local names
+func (d *contentRefDecoder) at(names ...string) bool {
+	if len(d.stack) != len(names)+1 {
+		return false
+	}
+	for i, name := range names {
+		if d.stack[i+1].Local != name {
+			return false
+		}
+	}
+	return true
+}
+
+// collectingText reports whether the canonical name of a term is open
+func (d *contentRefDecoder) collectingText() bool {
+	return d.term != nil && (d.at("tags", "tag", "term", "canonicalName") ||
+		d.at("primarySection", "canonicalName") || d.at("primaryTheme", "canonicalName"))
+}
+
+func (d *contentRefDecoder) currentTag() *tag {
+	return &d.contentRef.TagHolder.Tags[len(d.contentRef.TagHolder.Tags)-1]
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// attrValue returns the value of the last attribute with the local name, or value when there is none
+func attrValue(t xml.StartElement, local string, value string) string {
+	for _, attr := range t.Attr {
+		if attr.Name.Local == local {
+			value = attr.Value
+		}
+	}
+	return value
+}
+
+func intAttrValue(t xml.StartElement, local string, value int) (int, error) {
+	for _, attr := range t.Attr {
+		if attr.Name.Local != local {
+			continue
+		}
+		trimmed := strings.TrimSpace(attr.Value)
+		if trimmed == "" {
+			value = 0
+			continue
+		}
+		parsed, err := strconv.Atoi(trimmed)
+		if err != nil {
+			return value, err
+		}
+		value = parsed
+	}
+	return value, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeContentRefMatchesUnmarshal(t *testing.T) {
+	validUTF8XML, _ := base64.StdEncoding.DecodeString(validUTF8Metadata)
+	tests := []struct {
+		name string
+		xml  []byte
+	}{
+		{"README sample", []byte(readmeSampleMetadata)},
+		{"Methode article with a tag with two terms", validUTF8XML},
+		{"large document", largeMetadataXML(500)},
+		{"unprefixed elements, CDATA and nested text", []byte(`<contentRef created="2017-01-01T00:00:00Z"><!-- comment -->
+<primarySection taxonomy="Sections" id="1"><canonicalName>World <![CDATA[& More]]><b>ignored</b></canonicalName></primarySection>
+<tags><tag><term taxonomy="ON" id="2"><canonicalName>A</canonicalName></term><score relevance=" 90 " confidence=""/></tag></tags>
+<tags><tag><term taxonomy="PN" id="3"/></tag></tags>
+<primarySection taxonomy="Topics"/></contentRef> trailing`)},
+	}
+
+	for _, test := range tests {
+		expected := ContentRef{}
+		assert.NoError(t, xml.Unmarshal(test.xml, &expected), test.name)
+
+		actual, err := decodeContentRef(test.xml, xmlLimits)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, expected, actual, test.name)
+	}
+}
+
+func TestDecodeContentRefLimits(t *testing.T) {
+	document := largeMetadataXML(10)
+
+	_, err := decodeContentRef(document, XMLLimits{MaxBytes: 100})
+	assert.EqualError(t, err, fmt.Sprintf("Metadata XML has %d bytes, more than 100", len(document)))
+
+	_, err = decodeContentRef(document, XMLLimits{MaxElements: 12})
+	assert.EqualError(t, err, "Metadata XML has more than 12 elements at line 7, column 1 (byte offset 987)")
+
+	_, err = decodeContentRef([]byte("<a><b><c><d/></c></b></a>"), XMLLimits{MaxDepth: 3})
+	assert.EqualError(t, err, "Metadata XML is nested deeper than 3 elements at line 1, column 10 (byte offset 9)")
+
+	_, err = decodeContentRef(document, XMLLimits{})
+	assert.NoError(t, err)
+}
+
+func TestDecodeContentRefLocatesErrors(t *testing.T) {
+	tests := []struct {
+		xml      string
+		expected string
+	}{
+		{"<contentRef>\n  <tags>\n  </tag>", "XML syntax error: element <tags> closed by </tag> at line 3, column 3 (byte offset 24)"},
+		{"<contentRef>\n <tags><tag><score relevance=\"high\"/>", "strconv.Atoi: parsing \"high\": invalid syntax at line 2, column 13 (byte offset 25)"},
+		{"<contentRef>\n<tags>", "XML syntax error: unexpected EOF at line 2, column 7 (byte offset 19)"},
+		{"<contentRef><a b=c/></contentRef>", "XML syntax error: unquoted or missing attribute value in element at line 1, column 19 (byte offset 18)"},
+	}
+	for _, test := range tests {
+		_, err := decodeContentRef([]byte(test.xml), xmlLimits)
+		assert.EqualError(t, err, test.expected, test.xml)
+	}
+
+	_, err := decodeContentRef([]byte("  "), xmlLimits)
+	assert.Error(t, err, "A document without root element should fail")
+}
+
+// largeMetadataXML returns metadata XML with the number of tags, each on its own line
+func largeMetadataXML(tagCount int) []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<ns5:contentRef ns5:created="2016-12-29T14:54:10.000Z" xmlns:ns5="http://metadata.internal.ft.com/metadata/xsd/metadata_content_reference_v1.0.xsd" xmlns:ns4="http://metadata.internal.ft.com/metadata/xsd/metadata_term_v1.0.xsd" xmlns:ns6="http://metadata.internal.ft.com/metadata/xsd/metadata_tag_v1.0.xsd">
+<ns5:primarySection ns4:taxonomy="Sections" ns4:externalTermId="116" ns4:id="MTE2-U2VjdGlvbnM="><ns4:canonicalName>Comment</ns4:canonicalName></ns5:primarySection>
+<ns5:tags>
+`)
+	for i := 0; i < tagCount; i++ {
+		fmt.Fprintf(&b, `<ns6:tag><ns6:term ns4:taxonomy="ON" ns4:externalTermId="Nstein_ON_%d" ns4:id="T04tJWQ=-%d"><ns4:canonicalName>Organisation &amp; Co %d</ns4:canonicalName></ns6:term><ns6:score ns6:relevance="%d" ns6:confidence="%d"/></ns6:tag>`+"\n", i, i, i, i%100, (i+50)%100)
+	}
+	b.WriteString("</ns5:tags>\n</ns5:contentRef>\n")
+	return b.Bytes()
+}
+
+func BenchmarkDecodeContentRef(b *testing.B) {
+	document := largeMetadataXML(2000)
+	b.SetBytes(int64(len(document)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := decodeContentRef(document, xmlLimits); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUnmarshalContentRef is the xml.Unmarshal baseline of BenchmarkDecodeContentRef
+func BenchmarkUnmarshalContentRef(b *testing.B) {
+	document := largeMetadataXML(2000)
+	b.SetBytes(int64(len(document)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var contentRef ContentRef
+		if err := xml.Unmarshal(document, &contentRef); err != nil {
+			b.Fatal(err)
+		}
+	}
+}